@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/harvester"
+	"github.com/aymerick/charette/system"
+)
+
+// command represents a charette subcommand
+type command struct {
+	// command name
+	name string
+
+	// arguments usage
+	args string
+
+	// one line description
+	short string
+
+	// full description
+	long string
+
+	// flags registration
+	flags func(fs *flag.FlagSet)
+
+	// command execution
+	run func(options *core.Options, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:  "harvest",
+			short: "Select roms from no-intro archives and copy them to output directory",
+			long:  "Extracts no-intro archives found in input directory, selects the best rom of each game and moves it to output directory.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addDatFlags(fs)
+				addSelectionFlags(fs)
+				addBudgetFlags(fs)
+				addHarvestFlags(fs)
+				addFormatFlags(fs)
+				addPatchFlags(fs)
+				addExportFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runHarvest,
+		},
+		{
+			name:  "scan",
+			short: "List detected no-intro archives and systems",
+			long:  "Lists no-intro archives and extracted directories found in input directory, grouped by system, with their set date. Only the newest set of each system is listed, unless -merge-sets is set.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runScan,
+		},
+		{
+			name:  "systems",
+			short: "List supported systems",
+			long:  "Lists all supported systems, with their output directory name.",
+			flags: func(fs *flag.FlagSet) {},
+			run:   runSystems,
+		},
+		{
+			name:  "plan",
+			short: "Display roms that would be selected, without writing anything to output directory",
+			long:  "Extracts no-intro archives found in input directory and displays the best rom of each game, without moving any file to output directory.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addDatFlags(fs)
+				addSelectionFlags(fs)
+				addBudgetFlags(fs)
+				addPatchFlags(fs)
+				addExportFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runPlan,
+		},
+		{
+			name:  "report",
+			short: "Report games each system has and misses",
+			long:  "Extracts no-intro archives found in input directory and reports, for each system, the games with a rom in preferred regions, the games with only a rom in other regions, and the missing games with the reason why: skipped roms, curated lists, storage budget, or missing from input archives when DAT files are set. Nothing is written to output directory.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addDatFlags(fs)
+				addSelectionFlags(fs)
+				addBudgetFlags(fs)
+				addPatchFlags(fs)
+				addReportFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runReport,
+		},
+		{
+			name:  "diff",
+			args:  "<old> <new>",
+			short: "Compare two set versions or two reports",
+			long:  "Compares two no-intro archives or extracted directories of the same system, or two CSV reports written by the report command, and lists games that were added, removed or renamed, and games which selected rom changes with current options. Renamed games are detected with the CRC32 of selected roms.",
+			flags: func(fs *flag.FlagSet) {
+				addDiffFlags(fs)
+				addSelectionFlags(fs)
+				addPatchFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runDiff,
+		},
+		{
+			name:  "explain",
+			args:  "<game name | rom filename>...",
+			short: "Explain how roms are parsed and ranked",
+			long:  "Displays the full ranking of given roms with current options: region rank, video standard, alternative tag, version and skip reason of each candidate, then the winner and the rule that broke the tie. Game names are searched in input archives.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addSelectionFlags(fs)
+			},
+			run: runExplain,
+		},
+		{
+			name:  "verify",
+			short: "Check output directory",
+			long:  "Reports output files that would not be selected anymore with current options, files that are unknown to input archives and DAT files, corrupted files and roms with bad internal headers. Exits with status 1 if any file is reported.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addDatFlags(fs)
+				addSelectionFlags(fs)
+				addFormatFlags(fs)
+				addPatchFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runVerify,
+		},
+		{
+			name:  "clean",
+			short: "Report or quarantine unwanted files in output directory",
+			long:  "Reports output files that would not be selected anymore with current options, and files that are unknown to input archives and DAT files. Those files are moved to quarantine directory if set.",
+			flags: func(fs *flag.FlagSet) {
+				addInputFlags(fs)
+				addOutputFlags(fs)
+				addDatFlags(fs)
+				addQuarantineFlags(fs)
+				addSelectionFlags(fs)
+				addPatchFlags(fs)
+				addCommonFlags(fs)
+			},
+			run: runClean,
+		},
+	}
+}
+
+// findCommand returns command with given name, or nil if not found
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+// flagSet returns the flag set for that command
+func (cmd *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+
+	cmd.flags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: charette %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.long)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// printHeader displays charette version and directories
+func printHeader(options *core.Options) {
+	if !options.Quiet {
+		fmt.Printf("charette v%s\n", version)
+		fmt.Printf("   input: %s\n", options.Input)
+		fmt.Printf("   output: %s\n", options.Output)
+		fmt.Printf("   tmp dir: %s\n", options.Tmp)
+	}
+}
+
+// checkDirs returns an error if output or tmp directory is the input directory
+func checkDirs(options *core.Options) error {
+	if (options.Input == options.Output) || (options.Input == options.Tmp) {
+		return errors.New("Output and tmp directories can't be the same as input directory")
+	}
+
+	return nil
+}
+
+func runHarvest(options *core.Options, args []string) error {
+	printHeader(options)
+
+	if err := checkDirs(options); err != nil {
+		return err
+	}
+
+	return harvester.New(options).Run()
+}
+
+func runPlan(options *core.Options, args []string) error {
+	printHeader(options)
+
+	if err := checkDirs(options); err != nil {
+		return err
+	}
+
+	options.DryRun = true
+
+	return harvester.New(options).Run()
+}
+
+func runReport(options *core.Options, args []string) error {
+	printHeader(options)
+
+	if err := checkDirs(options); err != nil {
+		return err
+	}
+
+	options.DryRun = true
+	options.Report = true
+
+	return harvester.New(options).Run()
+}
+
+func runScan(options *core.Options, args []string) error {
+	systems, err := harvester.New(options).Scan()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	for _, infos := range system.SortedInfos(systems) {
+		for _, archive := range systems[infos] {
+			date := system.ArchiveDate(archive)
+			if date == "" {
+				date = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", infos.FullName(), infos.Dir, date, archive)
+		}
+	}
+
+	return w.Flush()
+}
+
+func runSystems(options *core.Options, args []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "MANUFACTURER\tNAME\tDIR\n")
+
+	for _, infos := range system.SupportedSystems {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", infos.Manufacturer, infos.Name, infos.Dir)
+	}
+
+	return w.Flush()
+}
+
+func runDiff(options *core.Options, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Expected two archives or reports to compare")
+	}
+
+	return harvester.New(options).Diff(args[0], args[1])
+}
+
+func runExplain(options *core.Options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("Missing game name or rom filename")
+	}
+
+	return harvester.New(options).Explain(args)
+}
+
+func runVerify(options *core.Options, args []string) error {
+	options.Quarantine = ""
+
+	nb, err := harvester.New(options).Verify()
+	if err != nil {
+		return err
+	}
+
+	if nb > 0 {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func runClean(options *core.Options, args []string) error {
+	return harvester.New(options).Clean()
+}
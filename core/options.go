@@ -1,14 +1,63 @@
 package core
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // Options holds the settings for Harvester
 type Options struct {
 	Input  string
 	Output string
 	Tmp    string
 
+	// process all dated copies of a system set, instead of the newest one only
+	MergeSets bool
+
+	// system of single archive input, detected from archive name or contents if empty
+	System string
+
+	// paths to DAT files or to directories containing DAT files
+	Dats []string
+
+	// path to directory where unwanted output files are moved by the clean command
+	Quarantine string
+
 	Regions []string
 	Strict  bool
 
+	// region scoring mode: RegionScoreBest or RegionScoreCoverage
+	RegionScoring string
+
+	// "World" roms match any preferred region
+	WorldAny bool
+
+	// preferred video standard: VideoPAL, VideoNTSC, or empty for no preference
+	Video string
+
+	// rank video standard above regions
+	VideoFirst bool
+
+	// path to manual overrides file
+	Overrides string
+
+	// paths to curated lists of games to include or to exclude
+	IncludeList string
+	ExcludeList string
+
+	// storage budget in bytes for all systems, 0 for no limit
+	MaxSize int64
+
+	// storage budgets in bytes, indexed by system full name, name or output directory
+	SystemMaxSizes map[string]int64
+
+	// storage budget priority weights, indexed by system full name, name or output directory
+	SystemWeights map[string]int
+
+	// preferred systems, when a game is found in several systems sharing the same output directory
+	PreferredSystems []string
+
 	KeepProto  bool
 	KeepBeta   bool
 	KeepSample bool
@@ -19,9 +68,180 @@ type Options struct {
 	Quiet bool
 	Debug bool
 	Unzip bool
+
+	// select roms without moving them to output directory
+	DryRun bool
+
+	// write a m3u playlist for each multi-part game
+	Playlists bool
+
+	// write a gamelist.xml file into each system output directory
+	Gamelist bool
+
+	// path to local media mirror
+	MediaDir string
+
+	// link media files instead of copying them
+	MediaLink bool
+
+	// inspect internal headers of selected roms
+	CheckHeaders bool
+
+	// byte order of selected N64 roms: "z64", "v64" or "n64", roms are not converted if empty
+	N64Format string
+
+	// paths to header skipper definitions files, or to directories containing them
+	HeaderSkippers []string
+
+	// header actions per system, indexed by system full name, name or output directory
+	HeaderActions map[string]string
+
+	// path to patches directory
+	Patches string
+
+	// how patched roms are written: "beside", "replace" or "softpatch"
+	PatchMode string
+
+	// path to directory where a DAT file of selected roms is written for each system
+	ExportDats string
+
+	// display the completeness report of each system instead of selected roms
+	Report bool
+
+	// path to CSV file where the completeness report is written
+	ReportCSV string
+}
+
+// patch modes
+const (
+	// patched rom is written next to the original one
+	PatchBeside = "beside"
+
+	// patched rom is written instead of the original one
+	PatchReplace = "replace"
+
+	// rom is not patched, the patch is copied next to it for frontends that patch at load time
+	PatchSoftpatch = "softpatch"
+)
+
+// header actions
+const (
+	// rom is copied as is
+	HeaderKeep = "keep"
+
+	// copier header is removed
+	HeaderStrip = "strip"
+
+	// header from DAT files is added to headerless roms
+	HeaderAdd = "add"
+
+	// header is replaced by the one from DAT files, or added if missing
+	HeaderNormalize = "normalize"
+)
+
+// ExtractN64Format returns the N64 byte order corresponding to given setting, eg: "v64", or an empty string if invalid
+func ExtractN64Format(str string) string {
+	switch format := strings.ToLower(strings.TrimSpace(str)); format {
+	case "z64", "v64", "n64":
+		return format
+	}
+
+	return ""
+}
+
+// ExtractHeaderAction returns the header action corresponding to given setting, or an empty string if invalid
+func ExtractHeaderAction(str string) string {
+	switch action := strings.ToLower(strings.TrimSpace(str)); action {
+	case HeaderKeep, HeaderStrip, HeaderAdd, HeaderNormalize:
+		return action
+	}
+
+	return ""
+}
+
+// ExtractPatchMode returns the patch mode corresponding to given setting, or an empty string if invalid
+func ExtractPatchMode(str string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(str)); mode {
+	case PatchBeside, PatchReplace, PatchSoftpatch:
+		return mode
+	}
+
+	return ""
+}
+
+// SelectionFlags returns the command line flags of the options that change which roms are selected, eg: "-regions=USA,Japan -strict"
+func (o *Options) SelectionFlags() string {
+	result := []string{"-regions=" + strings.Join(o.Regions, ",")}
+
+	bools := []struct {
+		name  string
+		value bool
+	}{
+		{"strict", o.Strict},
+		{"world-any", o.WorldAny},
+		{"video-first", o.VideoFirst},
+		{"keep-proto", o.KeepProto},
+		{"keep-beta", o.KeepBeta},
+		{"keep-sample", o.KeepSample},
+		{"keep-demo", o.KeepDemo},
+		{"keep-pirate", o.KeepPirate},
+		{"keep-promo", o.KeepPromo},
+	}
+
+	for _, b := range bools {
+		if b.value {
+			result = append(result, "-"+b.name)
+		}
+	}
+
+	strs := []struct {
+		name  string
+		value string
+	}{
+		{"region-scoring", o.RegionScoring},
+		{"video", o.Video},
+		{"prefer-systems", strings.Join(o.PreferredSystems, ",")},
+		{"overrides", o.Overrides},
+		{"include-list", o.IncludeList},
+		{"exclude-list", o.ExcludeList},
+		{"patches", o.Patches},
+	}
+
+	for _, str := range strs {
+		if str.value != "" {
+			result = append(result, fmt.Sprintf("-%s=%s", str.name, str.value))
+		}
+	}
+
+	if o.MaxSize > 0 {
+		result = append(result, fmt.Sprintf("-max-size=%d", o.MaxSize))
+	}
+
+	if len(o.SystemMaxSizes) > 0 {
+		sizes := []string{}
+		for name, size := range o.SystemMaxSizes {
+			sizes = append(sizes, fmt.Sprintf("%s=%d", name, size))
+		}
+
+		sort.Strings(sizes)
+		result = append(result, "-system-max-size="+strings.Join(sizes, ","))
+	}
+
+	return strings.Join(result, " ")
+}
+
+// UseBudget returns true if a storage budget is set
+func (o *Options) UseBudget() bool {
+	return (o.MaxSize > 0) || (len(o.SystemMaxSizes) > 0)
 }
 
 // NewOptions instanciates a new Options
 func NewOptions() *Options {
-	return &Options{}
+	return &Options{
+		RegionScoring:  RegionScoreBest,
+		PatchMode:      PatchBeside,
+		SystemMaxSizes: map[string]int64{},
+		SystemWeights:  map[string]int{},
+		HeaderActions:  map[string]string{},
+	}
 }
@@ -0,0 +1,19 @@
+package core
+
+import "testing"
+
+func TestSelectionFlags(t *testing.T) {
+	o := NewOptions()
+	o.Regions = []string{"USA", "Japan"}
+	o.Strict = true
+	o.KeepBeta = true
+	o.Video = VideoPAL
+	o.SystemMaxSizes["gba"] = 1 << 30
+	o.SystemMaxSizes["gb"] = 1 << 20
+
+	expected := "-regions=USA,Japan -strict -keep-beta -region-scoring=best -video=PAL -system-max-size=gb=1048576,gba=1073741824"
+
+	if result := o.SelectionFlags(); result != expected {
+		t.Errorf("Selection flags failed, got '%s' but expected '%s'", result, expected)
+	}
+}
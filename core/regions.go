@@ -2,49 +2,247 @@ package core
 
 import "strings"
 
+// video standards
+const (
+	VideoPAL  = "PAL"
+	VideoNTSC = "NTSC"
+)
+
+// region scoring modes
+const (
+	// only the best preferred region of a rom counts
+	RegionScoreBest = "best"
+
+	// roms covering more preferred regions win ties
+	RegionScoreCoverage = "coverage"
+)
+
+// Region represents a region found in no-intro rom names
+type Region struct {
+	Name string
+
+	// video standard, empty if unknown or inherited from parent regions
+	Video string
+
+	// parent regions, eg: "Europe" for "France"
+	Parents []string
+}
+
 var (
-	allowedRegions map[string]bool
+	// registered regions, indexed by name
+	regions map[string]*Region
+
+	// regions groups and alternative names, indexed by alias
+	aliases map[string][]string
 )
 
 func init() {
-	allowedRegions = map[string]bool{
-		"Asia":        true,
-		"Australia":   true,
-		"Brazil":      true,
-		"Canada":      true,
-		"China":       true,
-		"Denmark":     true,
-		"Europe":      true,
-		"Finland":     true,
-		"France":      true,
-		"Germany":     true,
-		"Hong Kong":   true,
-		"Italy":       true,
-		"Japan":       true,
-		"Korea":       true,
-		"Netherlands": true,
-		"Russia":      true,
-		"Spain":       true,
-		"Sweden":      true,
-		"Taiwan":      true,
-		"Unknown":     true,
-		"USA":         true,
-		"World":       true,
-	}
-}
-
-// ExtractRegions returns an array of regions
+	regions = map[string]*Region{}
+	aliases = map[string][]string{}
+
+	RegisterRegion("Asia", VideoNTSC)
+	RegisterRegion("Australia", VideoPAL)
+	RegisterRegion("Canada", VideoNTSC)
+	RegisterRegion("Europe", VideoPAL)
+	RegisterRegion("Japan", VideoNTSC)
+	RegisterRegion("Latin America", VideoNTSC)
+	RegisterRegion("Russia", VideoPAL)
+	RegisterRegion("Unknown", "")
+	RegisterRegion("USA", VideoNTSC)
+	RegisterRegion("World", "")
+
+	for _, name := range []string{
+		"Austria",
+		"Belgium",
+		"Croatia",
+		"France",
+		"Germany",
+		"Greece",
+		"Ireland",
+		"Italy",
+		"Netherlands",
+		"Poland",
+		"Portugal",
+		"Scandinavia",
+		"Spain",
+		"Switzerland",
+		"UK",
+	} {
+		RegisterRegion(name, "", "Europe")
+	}
+
+	for _, name := range []string{"Denmark", "Finland", "Norway", "Sweden"} {
+		RegisterRegion(name, "", "Scandinavia")
+	}
+
+	for _, name := range []string{"Korea", "Taiwan"} {
+		RegisterRegion(name, "", "Asia")
+	}
+
+	for _, name := range []string{"China", "Hong Kong", "India", "Singapore"} {
+		RegisterRegion(name, VideoPAL, "Asia")
+	}
+
+	for _, name := range []string{"Brazil", "Chile", "Mexico"} {
+		RegisterRegion(name, "", "Latin America")
+	}
+
+	RegisterRegion("Argentina", VideoPAL, "Latin America")
+	RegisterRegion("New Zealand", "", "Australia")
+
+	RegisterAlias("EU", "Europe")
+	RegisterAlias("EUR", "Europe")
+	RegisterAlias("US", "USA")
+	RegisterAlias("JP", "Japan")
+	RegisterAlias("JPN", "Japan")
+	RegisterAlias("United Kingdom", "UK")
+	RegisterAlias("PAL", "Europe", "Australia", "New Zealand")
+	RegisterAlias("NTSC-U", "USA", "Canada")
+	RegisterAlias("NTSC-J", "Japan", "Asia")
+}
+
+// RegisterRegion registers a new region, with its video standard and its parent regions. An empty video standard is inherited from parent regions.
+func RegisterRegion(name string, video string, parents ...string) {
+	regions[name] = &Region{
+		Name:    name,
+		Video:   video,
+		Parents: parents,
+	}
+}
+
+// RegisterAlias registers an alias for given regions. An alias can be used in preferred regions settings, but not in rom names.
+func RegisterAlias(alias string, names ...string) {
+	aliases[alias] = names
+}
+
+// IsRegion returns true if given name is a registered region
+func IsRegion(name string) bool {
+	return regions[name] != nil
+}
+
+// ExtractRegions returns an array of regions, with aliases expanded. Unknown regions are ignored, see UnknownRegions().
 func ExtractRegions(str string) []string {
 	result := []string{}
 
-	regions := strings.Split(str, ",")
-	for _, region := range regions {
+	for _, region := range strings.Split(str, ",") {
+		region = strings.TrimSpace(region)
+
+		names := []string{region}
+		if aliases[region] != nil {
+			names = aliases[region]
+		}
+
+		for _, name := range names {
+			if IsRegion(name) && !contains(result, name) {
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result
+}
+
+// UnknownRegions returns the values of given comma separated list that are neither a registered region nor an alias, eg: a typo in preferred regions settings
+func UnknownRegions(str string) []string {
+	result := []string{}
+
+	for _, region := range strings.Split(str, ",") {
+		region = strings.TrimSpace(region)
+
+		if (region != "") && !IsRegion(region) && (aliases[region] == nil) {
+			result = append(result, region)
+		}
+	}
+
+	return result
+}
+
+// ParseRegions returns an array of regions found in given rom name tag, eg: "USA, Europe". Aliases are ignored.
+func ParseRegions(tag string) []string {
+	result := []string{}
+
+	for _, region := range strings.Split(tag, ",") {
 		region = strings.TrimSpace(region)
 
-		if allowedRegions[region] {
+		if IsRegion(region) {
 			result = append(result, region)
 		}
 	}
 
 	return result
 }
+
+// Implies returns true if region is a sub region of given parent region, eg: "Sweden" implies "Scandinavia" and "Europe"
+func Implies(region string, parent string) bool {
+	r := regions[region]
+	if r == nil {
+		return false
+	}
+
+	for _, p := range r.Parents {
+		if (p == parent) || Implies(p, parent) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RegionVideo returns the video standard of given region, or an empty string if unknown
+func RegionVideo(name string) string {
+	r := regions[name]
+	if r == nil {
+		return ""
+	}
+
+	if r.Video != "" {
+		return r.Video
+	}
+
+	for _, p := range r.Parents {
+		if video := RegionVideo(p); video != "" {
+			return video
+		}
+	}
+
+	return ""
+}
+
+// ExtractVideo returns the video standard corresponding to given setting, eg: "pal", or an empty string if invalid
+func ExtractVideo(str string) string {
+	switch strings.ToUpper(strings.TrimSpace(str)) {
+	case VideoPAL:
+		return VideoPAL
+	case VideoNTSC:
+		return VideoNTSC
+	}
+
+	return ""
+}
+
+// ExtractRegionScoring returns the region scoring mode corresponding to given setting, or an empty string if invalid
+func ExtractRegionScoring(str string) string {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case RegionScoreBest:
+		return RegionScoreBest
+	case RegionScoreCoverage:
+		return RegionScoreCoverage
+	}
+
+	return ""
+}
+
+// Related returns true if a region implies the other one
+func Related(region1 string, region2 string) bool {
+	return Implies(region1, region2) || Implies(region2, region1)
+}
+
+func contains(ar []string, value string) bool {
+	for _, v := range ar {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -20,3 +20,91 @@ func TestExtractRegions(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractRegionsAliases(t *testing.T) {
+	result := ExtractRegions("EU, NTSC-U,Japan,USA,PAL")
+
+	expected := []string{"Europe", "USA", "Canada", "Japan", "Australia", "New Zealand"}
+
+	if len(result) != len(expected) {
+		t.Fatal(fmt.Sprintf("Failed to extract regions aliases, got '%v' but expected '%v'", result, expected))
+	}
+
+	for i, value := range expected {
+		if result[i] != value {
+			t.Errorf("Failed to extract regions aliases, got '%v' but expected '%v'", result, expected)
+		}
+	}
+}
+
+func TestUnknownRegions(t *testing.T) {
+	result := UnknownRegions("France, Eruope,EU,, NTSC-U,Prout")
+
+	expected := []string{"Eruope", "Prout"}
+
+	if fmt.Sprintf("%v", result) != fmt.Sprintf("%v", expected) {
+		t.Errorf("Failed to find unknown regions, got '%v' but expected '%v'", result, expected)
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	result := ParseRegions("USA, EU, Mexico")
+
+	expected := []string{"USA", "Mexico"}
+
+	if len(result) != len(expected) {
+		t.Fatal(fmt.Sprintf("Failed to parse regions, got '%v' but expected '%v'", result, expected))
+	}
+
+	for i, value := range expected {
+		if result[i] != value {
+			t.Errorf("Failed to parse regions, got '%v' but expected '%v'", result, expected)
+		}
+	}
+}
+
+func TestImplies(t *testing.T) {
+	tests := []struct {
+		region   string
+		parent   string
+		expected bool
+	}{
+		{"France", "Europe", true},
+		{"Sweden", "Scandinavia", true},
+		{"Sweden", "Europe", true},
+		{"Europe", "France", false},
+		{"France", "Germany", false},
+		{"Japan", "Asia", false},
+		{"Mexico", "Latin America", true},
+	}
+
+	for _, test := range tests {
+		if result := Implies(test.region, test.parent); result != test.expected {
+			t.Errorf("Failed to compute regions implication of '%s' and '%s', got %v but expected %v", test.region, test.parent, result, test.expected)
+		}
+	}
+}
+
+func TestRegionVideo(t *testing.T) {
+	tests := []struct {
+		region   string
+		expected string
+	}{
+		{"Europe", VideoPAL},
+		{"France", VideoPAL},
+		{"Sweden", VideoPAL},
+		{"USA", VideoNTSC},
+		{"Korea", VideoNTSC},
+		{"Hong Kong", VideoPAL},
+		{"Brazil", VideoNTSC},
+		{"New Zealand", VideoPAL},
+		{"World", ""},
+		{"Atlantis", ""},
+	}
+
+	for _, test := range tests {
+		if result := RegionVideo(test.region); result != test.expected {
+			t.Errorf("Failed to get video standard of '%s', got '%s' but expected '%s'", test.region, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// size units
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses given size setting, eg: "32G", "1.5GB", "700M" or "1024"
+func ParseSize(str string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(str))
+	s = strings.TrimSuffix(s, "IB")
+
+	factor := int64(1)
+
+	if (len(s) > 1) && strings.HasSuffix(s, "B") {
+		s = s[:len(s)-1]
+	}
+
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(s[:len(s)-1])
+			factor = unit.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if (err != nil) || (value < 0) {
+		return 0, fmt.Errorf("Invalid size: %s", str)
+	}
+
+	return int64(value * float64(factor)), nil
+}
+
+// FormatSize returns a human readable representation of given size, eg: "1.5 GB"
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits[:len(sizeUnits)-1] {
+		if size >= unit.factor {
+			return fmt.Sprintf("%.1f %sB", float64(size)/float64(unit.factor), unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d B", size)
+}
+
+// ParseSettings parses given per system settings, eg: "gb=2G,Nintendo - Game Boy Advance=8G", and returns values indexed by system name
+func ParseSettings(str string) (map[string]string, error) {
+	result := map[string]string{}
+
+	for _, setting := range strings.Split(str, ",") {
+		if strings.TrimSpace(setting) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(setting, "=", 2)
+		if (len(parts) != 2) || (strings.TrimSpace(parts[0]) == "") {
+			return result, fmt.Errorf("Invalid system setting: %s", setting)
+		}
+
+		result[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return result, nil
+}
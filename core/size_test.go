@@ -0,0 +1,66 @@
+package core
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected int64
+		valid    bool
+	}{
+		{"32G", 32 << 30, true},
+		{"32GB", 32 << 30, true},
+		{"32 GiB", 32 << 30, true},
+		{"1.5g", 3 << 29, true},
+		{"700M", 700 << 20, true},
+		{"64k", 64 << 10, true},
+		{"1024", 1024, true},
+		{"1024B", 1024, true},
+		{"2T", 2 << 40, true},
+		{"", 0, false},
+		{"big", 0, false},
+		{"-1G", 0, false},
+	}
+
+	for _, test := range tests {
+		result, err := ParseSize(test.str)
+		if (err == nil) != test.valid {
+			t.Errorf("Failed to parse size '%s', got error '%v'", test.str, err)
+		} else if result != test.expected {
+			t.Errorf("Failed to parse size '%s', got %d but expected %d", test.str, result, test.expected)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KB"},
+		{700 << 20, "700.0 MB"},
+		{32 << 30, "32.0 GB"},
+	}
+
+	for _, test := range tests {
+		if result := FormatSize(test.size); result != test.expected {
+			t.Errorf("Failed to format size %d, got '%s' but expected '%s'", test.size, result, test.expected)
+		}
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	result, err := ParseSettings("gb=2G, Nintendo - Game Boy Advance = 8G")
+	if err != nil {
+		t.Fatal("Failed to parse settings", err)
+	}
+
+	if (len(result) != 2) || (result["gb"] != "2G") || (result["Nintendo - Game Boy Advance"] != "8G") {
+		t.Errorf("Failed to parse settings, got %v", result)
+	}
+
+	if _, err := ParseSettings("gb"); err == nil {
+		t.Errorf("Invalid settings should fail")
+	}
+}
@@ -0,0 +1,258 @@
+package curated
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/rom"
+)
+
+// List represents a curated list of games, grouped by system. A list file contains one game name, rom name or DAT hash per line, in sections named after systems, eg:
+//
+//	# comment
+//	[Nintendo - Game Boy]
+//	Tetris
+//	Super Mario Land 2 - 6 Golden Coins (USA, Europe) (Rev B)
+//	46DF91AD
+//
+// Entries before the first section apply to all systems.
+type List struct {
+	Entries []*Entry
+
+	// file path
+	File string
+}
+
+// Entry represents a game in a curated list
+type Entry struct {
+	// section name: system full name, name or output directory, empty for all systems
+	System string
+
+	// game name, rom name or hash, as found in list file
+	Value string
+
+	// line number in list file
+	Line int
+
+	// game names resolved from DAT files, for hash entries
+	Names []string
+
+	// true if entry matched a game
+	Matched bool
+}
+
+// New instanciates a new List
+func New() *List {
+	return &List{}
+}
+
+// Load reads list file at given path
+func Load(filePath string) (*List, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Parse(data)
+	result.File = filePath
+
+	return result, nil
+}
+
+// Parse parses given list content
+func Parse(data []byte) *List {
+	result := New()
+	section := ""
+	line := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line++
+
+		str := strings.TrimSpace(scanner.Text())
+		if (str == "") || strings.HasPrefix(str, "#") || strings.HasPrefix(str, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(str, "[") && strings.HasSuffix(str, "]") {
+			section = strings.TrimSpace(str[1 : len(str)-1])
+			continue
+		}
+
+		result.Entries = append(result.Entries, &Entry{
+			System: section,
+			Value:  str,
+			Line:   line,
+		})
+	}
+
+	return result
+}
+
+// ResolveHashes finds in given DATs the game names of hash entries
+func (l *List) ResolveHashes(dats []*dat.Dat) {
+	if l == nil {
+		return
+	}
+
+	for _, e := range l.Entries {
+		if !e.IsHash() {
+			continue
+		}
+
+		for _, d := range dats {
+			if g, _ := d.FindHash(e.Value); g != nil {
+				e.Names = append(e.Names, g.Name)
+			}
+		}
+	}
+}
+
+// Covers returns true if that list has entries for a system with one of given names
+func (l *List) Covers(systemNames []string) bool {
+	return len(l.entries(systemNames)) > 0
+}
+
+// HaveCRC returns true if that list has CRC32 entries for a system with one of given names
+func (l *List) HaveCRC(systemNames []string) bool {
+	for _, e := range l.entries(systemNames) {
+		if e.IsCRC() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Find returns the first entry matching given game name or one of given roms CRC32, in a system with one of given names, or nil if not found
+func (l *List) Find(systemNames []string, name string, crcs []string) *Entry {
+	key := rom.NormalizeTitle(name)
+
+	for _, e := range l.entries(systemNames) {
+		if e.match(key, crcs) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Rank returns the position of given entry in that list
+func (l *List) Rank(e *Entry) int {
+	for i, v := range l.Entries {
+		if v == e {
+			return i
+		}
+	}
+
+	return len(l.Entries)
+}
+
+// Unmatched returns entries that matched no game
+func (l *List) Unmatched() []*Entry {
+	result := []*Entry{}
+
+	if l != nil {
+		for _, e := range l.Entries {
+			if !e.Matched {
+				result = append(result, e)
+			}
+		}
+	}
+
+	return result
+}
+
+// entries returns entries for a system with one of given names
+func (l *List) entries(systemNames []string) []*Entry {
+	result := []*Entry{}
+
+	if l == nil {
+		return result
+	}
+
+	for _, e := range l.Entries {
+		if e.System == "" {
+			result = append(result, e)
+			continue
+		}
+
+		for _, name := range systemNames {
+			if strings.EqualFold(e.System, name) {
+				result = append(result, e)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// String implements fmt.Stringer
+func (e *Entry) String() string {
+	if e.System == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Value)
+	}
+
+	return fmt.Sprintf("line %d: [%s] %s", e.Line, e.System, e.Value)
+}
+
+// IsHash returns true if entry is a CRC32, MD5 or SHA1 hash
+func (e *Entry) IsHash() bool {
+	switch len(e.Value) {
+	case 8, 32, 40:
+		return isHex(e.Value)
+	}
+
+	return false
+}
+
+// IsCRC returns true if entry is a CRC32 hash
+func (e *Entry) IsCRC() bool {
+	return (len(e.Value) == 8) && isHex(e.Value)
+}
+
+// match returns true if entry matches given normalized game name or one of given CRC32
+func (e *Entry) match(key string, crcs []string) bool {
+	if e.IsHash() {
+		for _, crc := range crcs {
+			if strings.EqualFold(e.Value, crc) {
+				return true
+			}
+		}
+
+		for _, name := range e.Names {
+			if gameKey(name) == key {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return gameKey(e.Value) == key
+}
+
+// gameKey returns the normalized title of given game or rom name, without tags
+func gameKey(name string) string {
+	if i := strings.Index(name, " ("); i > 0 {
+		name = name[:i]
+	}
+
+	return rom.NormalizeTitle(name)
+}
+
+// isHex returns true if given string only contains hexadecimal digits
+func isHex(str string) bool {
+	for _, c := range str {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+
+	return true
+}
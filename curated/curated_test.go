@@ -0,0 +1,124 @@
+package curated
+
+import (
+	"testing"
+
+	"github.com/aymerick/charette/dat"
+)
+
+const sample = `# my top games
+Tetris
+
+[Nintendo - Game Boy]
+Legend of Zelda, The - Link's Awakening (France)
+Pokemon: Red Version
+0CF2A2A5
+
+[gba]
+; sha1 of a rom
+74591CC9501AF93873F9A5D3EB12DA12C0723BBC
+Unknown Game
+`
+
+func TestParse(t *testing.T) {
+	l := Parse([]byte(sample))
+
+	if len(l.Entries) != 6 {
+		t.Fatalf("List parsing failed, got %d entries but expected 6", len(l.Entries))
+	}
+
+	tests := []struct {
+		system string
+		value  string
+		line   int
+	}{
+		{"", "Tetris", 2},
+		{"Nintendo - Game Boy", "Legend of Zelda, The - Link's Awakening (France)", 5},
+		{"gba", "Unknown Game", 12},
+	}
+
+	for _, test := range tests {
+		found := false
+
+		for _, e := range l.Entries {
+			if (e.System == test.system) && (e.Value == test.value) && (e.Line == test.line) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("List parsing failed, entry not found: [%s] %s (line %d)", test.system, test.value, test.line)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	l := Parse([]byte(sample))
+
+	gb := []string{"gb", "Nintendo - Game Boy", "Game Boy"}
+	gba := []string{"gba", "Nintendo - Game Boy Advance", "Game Boy Advance"}
+
+	tests := []struct {
+		systems  []string
+		name     string
+		crcs     []string
+		expected string
+	}{
+		{gb, "Tetris", nil, "Tetris"},
+		{gba, "tetris", nil, "Tetris"},
+		{gb, "Legend of Zelda, The - Link's Awakening", nil, "Legend of Zelda, The - Link's Awakening (France)"},
+		{gb, "The Legend of Zelda: Link's Awakening", nil, "Legend of Zelda, The - Link's Awakening (France)"},
+		{gb, "Pokemon - Red Version", nil, "Pokemon: Red Version"},
+		{gba, "Pokemon - Red Version", nil, ""},
+		{gb, "Alleyway", []string{"0cf2a2a5"}, "0CF2A2A5"},
+		{gb, "Alleyway", []string{"12345678"}, ""},
+		{gba, "Tetris DX", nil, ""},
+	}
+
+	for _, test := range tests {
+		result := ""
+		if e := l.Find(test.systems, test.name, test.crcs); e != nil {
+			result = e.Value
+		}
+
+		if result != test.expected {
+			t.Errorf("List lookup failed for '%s' in %v, got '%s' but expected '%s'", test.name, test.systems, result, test.expected)
+		}
+	}
+
+	if !l.Covers(gba) || !l.HaveCRC(gb) || l.HaveCRC(gba) {
+		t.Errorf("List systems check failed")
+	}
+}
+
+func TestResolveHashes(t *testing.T) {
+	l := Parse([]byte(sample))
+
+	d := &dat.Dat{
+		Games: []*dat.Game{
+			{Name: "Metroid Fusion (USA)", Roms: []*dat.Rom{{Name: "Metroid Fusion (USA).gba", SHA1: "74591cc9501af93873f9a5d3eb12da12c0723bbc"}}},
+		},
+	}
+
+	l.ResolveHashes([]*dat.Dat{d})
+
+	gba := []string{"gba"}
+	if e := l.Find(gba, "Metroid Fusion", nil); (e == nil) || (e.Value != "74591CC9501AF93873F9A5D3EB12DA12C0723BBC") {
+		t.Errorf("Hash resolution failed, got '%v'", e)
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	l := Parse([]byte(sample))
+
+	l.Find([]string{"gb"}, "Tetris", nil).Matched = true
+
+	if result := l.Unmatched(); len(result) != 5 {
+		t.Errorf("Unmatched entries failed, got %d entries but expected 5", len(result))
+	}
+
+	var empty *List
+	if empty.Covers([]string{"gb"}) || (empty.Find([]string{"gb"}, "Tetris", nil) != nil) {
+		t.Errorf("Lookup in nil list failed")
+	}
+}
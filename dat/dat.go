@@ -0,0 +1,224 @@
+package dat
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// doctype is the Logiqx DAT document type declaration
+const doctype = `<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">`
+
+// Dat represents a Logiqx XML DAT file, as published by no-intro
+type Dat struct {
+	XMLName xml.Name `xml:"datafile"`
+	Header  Header   `xml:"header"`
+	Games   []*Game  `xml:"game"`
+
+	// file path
+	File string `xml:"-"`
+}
+
+// Header represents a DAT header
+type Header struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	Version     string `xml:"version,omitempty"`
+	Date        string `xml:"date,omitempty"`
+	Author      string `xml:"author,omitempty"`
+	Homepage    string `xml:"homepage,omitempty"`
+	URL         string `xml:"url,omitempty"`
+	Comment     string `xml:"comment,omitempty"`
+
+	// header skipper definition used by that DAT, if any
+	ClrMamePro *ClrMamePro `xml:"clrmamepro,omitempty"`
+}
+
+// ClrMamePro represents the ClrMamePro settings of a DAT header
+type ClrMamePro struct {
+	Header string `xml:"header,attr,omitempty"`
+}
+
+// Game represents a game in a DAT
+type Game struct {
+	Name        string `xml:"name,attr"`
+	CloneOf     string `xml:"cloneof,attr,omitempty"`
+	Description string `xml:"description"`
+	Roms        []*Rom `xml:"rom"`
+}
+
+// Rom represents a rom file in a DAT
+type Rom struct {
+	Name   string `xml:"name,attr"`
+	Size   int64  `xml:"size,attr,omitempty"`
+	CRC    string `xml:"crc,attr,omitempty"`
+	MD5    string `xml:"md5,attr,omitempty"`
+	SHA1   string `xml:"sha1,attr,omitempty"`
+	Status string `xml:"status,attr,omitempty"`
+
+	// rom header as hexadecimal bytes, for roms distributed without their header, eg: "4E 45 53 1A 02 01 01 00 00 00 00 00 00 00 00 00"
+	Header string `xml:"header,attr,omitempty"`
+}
+
+// New instanciates a new Dat
+func New() *Dat {
+	return &Dat{}
+}
+
+// Load reads DAT file at given path
+func Load(filePath string) (*Dat, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result.File = filePath
+
+	return result, nil
+}
+
+// Parse parses given DAT content
+func Parse(data []byte) (*Dat, error) {
+	result := New()
+	if err := xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadAll reads DAT files at given paths, a path can be either a DAT file or a directory containing DAT files
+func LoadAll(paths []string) ([]*Dat, error) {
+	result := []*Dat{}
+
+	for _, p := range paths {
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			return result, err
+		}
+
+		files := []string{p}
+
+		if fileInfo.IsDir() {
+			infos, err := ioutil.ReadDir(p)
+			if err != nil {
+				return result, err
+			}
+
+			files = []string{}
+			for _, info := range infos {
+				if !info.IsDir() && IsDatFile(info.Name()) {
+					files = append(files, path.Join(p, info.Name()))
+				}
+			}
+		}
+
+		for _, file := range files {
+			d, err := Load(file)
+			if err != nil {
+				return result, err
+			}
+
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
+// Write writes DAT to given file path, as a Logiqx XML DAT file
+func (d *Dat) Write(filePath string) error {
+	data, err := xml.MarshalIndent(d, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	data = append([]byte(xml.Header+doctype+"\n"), data...)
+	data = append(data, '\n')
+
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+// IsDatFile returns true if given file name has a DAT file extension
+func IsDatFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return (ext == ".dat") || (ext == ".xml")
+}
+
+// SkipperFile returns the header skipper definition file name used by that DAT, or an empty string if none
+func (d *Dat) SkipperFile() string {
+	if d.Header.ClrMamePro == nil {
+		return ""
+	}
+
+	return d.Header.ClrMamePro.Header
+}
+
+// HeaderBytes returns the decoded rom header, or nil if none or invalid
+func (r *Rom) HeaderBytes() []byte {
+	result, err := hex.DecodeString(strings.Replace(r.Header, " ", "", -1))
+	if (err != nil) || (len(result) == 0) {
+		return nil
+	}
+
+	return result
+}
+
+// FindGame returns the game with given name, or nil if not found
+func (d *Dat) FindGame(name string) *Game {
+	for _, g := range d.Games {
+		if g.Name == name {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// FindRom returns the rom with given file name, and the game that contains it
+func (d *Dat) FindRom(fileName string) (*Game, *Rom) {
+	for _, g := range d.Games {
+		for _, r := range g.Roms {
+			if r.Name == fileName {
+				return g, r
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// FindCRC returns the rom with given CRC32, and the game that contains it
+func (d *Dat) FindCRC(crc string) (*Game, *Rom) {
+	for _, g := range d.Games {
+		for _, r := range g.Roms {
+			if strings.EqualFold(r.CRC, crc) {
+				return g, r
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// FindHash returns the rom with given CRC32, MD5 or SHA1, and the game that contains it
+func (d *Dat) FindHash(hash string) (*Game, *Rom) {
+	for _, g := range d.Games {
+		for _, r := range g.Roms {
+			if strings.EqualFold(r.CRC, hash) || strings.EqualFold(r.MD5, hash) || strings.EqualFold(r.SHA1, hash) {
+				return g, r
+			}
+		}
+	}
+
+	return nil, nil
+}
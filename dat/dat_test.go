@@ -0,0 +1,122 @@
+package dat
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+const sample = `<?xml version="1.0"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/Dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Game Boy</name>
+		<description>Nintendo - Game Boy</description>
+		<version>20240101-123456</version>
+		<author>no-intro</author>
+	</header>
+	<game name="Tetris (World) (Rev A)">
+		<description>Tetris (World) (Rev A)</description>
+		<rom name="Tetris (World) (Rev A).gb" size="32768" crc="46DF91AD" md5="982ED5D2B12A0377EB14BCDC4123744E" sha1="74591CC9501AF93873F9A5D3EB12DA12C0723BBC"/>
+	</game>
+	<game name="Alleyway (World)">
+		<description>Alleyway (World)</description>
+		<rom name="Alleyway (World).gb" size="32768" crc="0CF2A2A5"/>
+	</game>
+</datafile>`
+
+func TestParse(t *testing.T) {
+	d, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	if d.Header.Name != "Nintendo - Game Boy" {
+		t.Errorf("Header parsing failed, got '%v'", d.Header.Name)
+	}
+
+	if len(d.Games) != 2 {
+		t.Fatalf("Games parsing failed, got %d games but expected 2", len(d.Games))
+	}
+
+	if g, r := d.FindRom("Alleyway (World).gb"); (g == nil) || (r.CRC != "0CF2A2A5") {
+		t.Errorf("Rom lookup failed, got '%v'", r)
+	}
+
+	if g, r := d.FindCRC("46df91ad"); (g == nil) || (g.Name != "Tetris (World) (Rev A)") || (r.Size != 32768) {
+		t.Errorf("CRC lookup failed, got '%v'", g)
+	}
+
+	for _, hash := range []string{"46DF91AD", "982ed5d2b12a0377eb14bcdc4123744e", "74591CC9501AF93873F9A5D3EB12DA12C0723BBC"} {
+		if g, _ := d.FindHash(hash); (g == nil) || (g.Name != "Tetris (World) (Rev A)") {
+			t.Errorf("Hash lookup failed for '%s', got '%v'", hash, g)
+		}
+	}
+}
+
+const headeredSample = `<?xml version="1.0"?>
+<datafile>
+	<header>
+		<name>Nintendo - Nintendo Entertainment System (Headerless)</name>
+		<description>Nintendo - Nintendo Entertainment System (Headerless)</description>
+		<clrmamepro header="No-Intro_NES.xml"/>
+	</header>
+	<game name="Super Mario Bros. (World)">
+		<description>Super Mario Bros. (World)</description>
+		<rom name="Super Mario Bros. (World).nes" size="40960" crc="3337EC46" header="4E 45 53 1A 02 01 01 00 00 00 00 00 00 00 00 00"/>
+	</game>
+</datafile>`
+
+func TestParseHeaders(t *testing.T) {
+	d, err := Parse([]byte(headeredSample))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	if d.SkipperFile() != "No-Intro_NES.xml" {
+		t.Errorf("Header skipper parsing failed, got '%s'", d.SkipperFile())
+	}
+
+	_, r := d.FindCRC("3337EC46")
+	if header := r.HeaderBytes(); (len(header) != 16) || (string(header[:4]) != "NES\x1a") {
+		t.Errorf("Rom header parsing failed, got '%v'", header)
+	}
+
+	if d, _ := Parse([]byte(sample)); d.SkipperFile() != "" {
+		t.Errorf("Header skipper parsing failed, got '%s'", d.SkipperFile())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-dats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	d.Header.Comment = "-regions=USA"
+	filePath := path.Join(dir, "gb.dat")
+
+	if err := d.Write(filePath); err != nil {
+		t.Fatal("Write failed", err)
+	}
+
+	result, err := Load(filePath)
+	if err != nil {
+		t.Fatal("Load failed", err)
+	}
+
+	if (result.Header.Comment != "-regions=USA") || (len(result.Games) != 2) {
+		t.Errorf("Write failed, got '%+v'", result.Header)
+	}
+
+	if g := result.FindGame("Tetris (World) (Rev A)"); (g == nil) || (g.Roms[0].SHA1 != "74591CC9501AF93873F9A5D3EB12DA12C0723BBC") {
+		t.Errorf("Write lost game, got '%v'", g)
+	}
+}
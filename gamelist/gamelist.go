@@ -0,0 +1,223 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// FileName is the gamelist file name expected by EmulationStation
+	FileName = "gamelist.xml"
+)
+
+// Gamelist represents an EmulationStation gamelist
+type Gamelist struct {
+	XMLName xml.Name `xml:"gameList"`
+	Games   []*Game  `xml:"game"`
+
+	// all other elements, eg: folders
+	Extra []Element `xml:",any"`
+}
+
+// Game represents a game entry in a gamelist
+type Game struct {
+	ID     string `xml:"id,attr,omitempty"`
+	Source string `xml:"source,attr,omitempty"`
+
+	Path      string `xml:"path"`
+	Name      string `xml:"name"`
+	Desc      string `xml:"desc,omitempty"`
+	Image     string `xml:"image,omitempty"`
+	Thumbnail string `xml:"thumbnail,omitempty"`
+	Region    string `xml:"region,omitempty"`
+	Lang      string `xml:"lang,omitempty"`
+	Version   string `xml:"version,omitempty"`
+
+	// all other elements, eg: scraped images and descriptions
+	Extra []Element `xml:",any"`
+}
+
+// Element represents an element that is not handled by charette, but that must be kept as is
+type Element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+// New instanciates a new Gamelist
+func New() *Gamelist {
+	return &Gamelist{}
+}
+
+// Load reads gamelist at given file path, an empty gamelist is returned if that file does not exist
+func Load(filePath string) (*Gamelist, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(), nil
+		}
+
+		return nil, err
+	}
+
+	result := New()
+	if err := xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// NewGame instanciates a new gamelist entry for given rom file name
+func NewGame(fileName string, name string) *Game {
+	return &Game{
+		Path: "./" + fileName,
+		Name: name,
+	}
+}
+
+// FileName returns the rom file name of that entry
+func (g *Game) FileName() string {
+	return path.Base(g.Path)
+}
+
+// Find returns the entry for given rom file name, or nil if not found
+func (gl *Gamelist) Find(fileName string) *Game {
+	for _, g := range gl.Games {
+		if g.FileName() == fileName {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// Set adds given entry, or merges it with the existing entry for the same rom file: scraped elements, names and media are kept, fields known by charette are updated
+func (gl *Gamelist) Set(entry *Game) *Game {
+	g := gl.Find(entry.FileName())
+	if g == nil {
+		gl.Games = append(gl.Games, entry)
+		return entry
+	}
+
+	if g.Name == "" {
+		g.Name = entry.Name
+	}
+
+	if g.Desc == "" {
+		g.Desc = entry.Desc
+	}
+
+	if g.Image == "" {
+		g.Image = entry.Image
+	}
+
+	if g.Thumbnail == "" {
+		g.Thumbnail = entry.Thumbnail
+	}
+
+	g.Region = entry.Region
+	g.Lang = entry.Lang
+	g.Version = entry.Version
+
+	return g
+}
+
+// Remove removes the entry for given rom file name, and returns it
+func (gl *Gamelist) Remove(fileName string) *Game {
+	for i, g := range gl.Games {
+		if g.FileName() == fileName {
+			gl.Games = append(gl.Games[:i], gl.Games[i+1:]...)
+			return g
+		}
+	}
+
+	return nil
+}
+
+// Rename moves the entry of given old rom file name to given new rom file name, so that scraped metadata follow a replaced rom. Nothing is done if there is no entry for the old file, or if there is already an entry for the new file. Returns true if the entry was moved.
+func (gl *Gamelist) Rename(oldFileName string, newFileName string) bool {
+	g := gl.Find(oldFileName)
+	if (g == nil) || (gl.Find(newFileName) != nil) {
+		return false
+	}
+
+	g.Path = strings.TrimSuffix(g.Path, oldFileName) + newFileName
+
+	return true
+}
+
+// Prune removes all entries with a rom file that does not exist anymore, and returns them. Relative paths are resolved in given directory, and absolute paths are checked as is. Entries with a path that can't be resolved, eg: relative to EmulationStation home directory, are kept.
+func (gl *Gamelist) Prune(dir string) []*Game {
+	result := []*Game{}
+	games := []*Game{}
+
+	for _, g := range gl.Games {
+		if filePath := g.localPath(dir); filePath != "" {
+			if _, err := os.Stat(filePath); os.IsNotExist(err) {
+				result = append(result, g)
+				continue
+			}
+		}
+
+		games = append(games, g)
+	}
+
+	gl.Games = games
+
+	return result
+}
+
+// localPath returns the rom file path of that entry, with a relative path resolved in given directory, or an empty string if that path can't be resolved, eg: "~/RetroPie/roms/gb/Tetris (World).zip"
+func (g *Game) localPath(dir string) string {
+	switch {
+	case strings.HasPrefix(g.Path, "~"):
+		return ""
+	case filepath.IsAbs(g.Path):
+		return g.Path
+	default:
+		return path.Join(dir, strings.TrimPrefix(g.Path, "./"))
+	}
+}
+
+// Write writes gamelist to given file path, with entries sorted by name
+func (gl *Gamelist) Write(filePath string) error {
+	sort.Sort(gamesByName(gl.Games))
+
+	data, err := xml.MarshalIndent(gl, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	data = append([]byte(xml.Header), data...)
+	data = append(data, '\n')
+
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+// gamesByName sorts gamelist entries by name
+type gamesByName []*Game
+
+// Implements sort.Interface
+func (a gamesByName) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a gamesByName) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a gamesByName) Less(i, j int) bool {
+	if a[i].Name != a[j].Name {
+		return a[i].Name < a[j].Name
+	}
+
+	return a[i].Path < a[j].Path
+}
@@ -0,0 +1,131 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const scraped = `<?xml version="1.0"?>
+<gameList>
+	<game id="1234" source="theGamesDB.net">
+		<path>./Tetris (World) (Rev A).zip</path>
+		<name>Tetris</name>
+		<desc>Falling blocks.</desc>
+		<image>~/.emulationstation/downloaded_images/gb/Tetris-image.jpg</image>
+	</game>
+	<game>
+		<path>./Alleyway (World).zip</path>
+		<name>Alleyway</name>
+	</game>
+</gameList>`
+
+func TestGamelistSet(t *testing.T) {
+	gl := New()
+	if err := xml.Unmarshal([]byte(scraped), gl); err != nil {
+		t.Fatal("Unmarshal failed", err)
+	}
+
+	g := gl.Set(&Game{Path: "./Tetris (World) (Rev A).zip", Name: "Tetris (Rev A)", Region: "World", Lang: "en", Version: "Rev A"})
+	gl.Set(NewGame("Dr. Mario (World).zip", "Dr. Mario"))
+
+	if len(gl.Games) != 3 {
+		t.Fatalf("Gamelist merge failed, got %d entries but expected 3", len(gl.Games))
+	}
+
+	if (g.ID != "1234") || (g.Name != "Tetris") || (g.Region != "World") || (g.Version != "Rev A") {
+		t.Errorf("Gamelist merge failed, got '%+v'", g)
+	}
+
+	data, err := xml.Marshal(gl)
+	if err != nil {
+		t.Fatal("Marshal failed", err)
+	}
+
+	for _, expected := range []string{"<desc>Falling blocks.</desc>", "<image>~/.emulationstation/downloaded_images/gb/Tetris-image.jpg</image>", "<path>./Dr. Mario (World).zip</path>"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Gamelist merge lost '%s': %s", expected, string(data))
+		}
+	}
+
+	if gl.Remove("Alleyway (World).zip") == nil || len(gl.Games) != 2 {
+		t.Errorf("Gamelist entry removal failed")
+	}
+}
+
+func TestGamelistRename(t *testing.T) {
+	gl := New()
+	if err := xml.Unmarshal([]byte(scraped), gl); err != nil {
+		t.Fatal("Unmarshal failed", err)
+	}
+
+	if !gl.Rename("Tetris (World) (Rev A).zip", "Tetris (Japan) (En) (Rev B).zip") {
+		t.Fatalf("Gamelist entry rename failed")
+	}
+
+	g := gl.Find("Tetris (Japan) (En) (Rev B).zip")
+	if (g == nil) || (g.Path != "./Tetris (Japan) (En) (Rev B).zip") || (g.Desc != "Falling blocks.") {
+		t.Errorf("Gamelist entry rename failed, got '%+v'", g)
+	}
+
+	if gl.Find("Tetris (World) (Rev A).zip") != nil {
+		t.Errorf("Gamelist entry rename kept the old entry")
+	}
+
+	if gl.Rename("Alleyway (World).zip", "Tetris (Japan) (En) (Rev B).zip") {
+		t.Errorf("Gamelist entry rename should not overwrite an existing entry")
+	}
+
+	if gl.Rename("Dr. Mario (World).zip", "Dr. Mario (Japan).zip") {
+		t.Errorf("Gamelist entry rename should fail without an entry")
+	}
+}
+
+func TestGamelistPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-gamelist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, fileName := range []string{"Tetris (World) (Rev A).zip", "Alleyway (World).zip"} {
+		if err := ioutil.WriteFile(path.Join(dir, fileName), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		pruned bool
+	}{
+		{"./Tetris (World) (Rev A).zip", false},
+		{"Tetris (World) (Rev A).zip", false},
+		{"./Dr. Mario (World).zip", true},
+		{path.Join(dir, "Alleyway (World).zip"), false},
+		{path.Join(dir, "Kirby's Dream Land (USA, Europe).zip"), true},
+		{"~/RetroPie/roms/gb/Dr. Mario (World).zip", false},
+	}
+
+	gl := New()
+	for _, test := range tests {
+		gl.Games = append(gl.Games, &Game{Path: test.path})
+	}
+
+	pruned := map[string]bool{}
+	for _, g := range gl.Prune(dir) {
+		pruned[g.Path] = true
+	}
+
+	for _, test := range tests {
+		if pruned[test.path] != test.pruned {
+			t.Errorf("Gamelist prune failed for '%s', got %v but expected %v", test.path, pruned[test.path], test.pruned)
+		}
+	}
+
+	if len(gl.Games)+len(pruned) != len(tests) {
+		t.Errorf("Gamelist prune failed, got %d entries and %d pruned", len(gl.Games), len(pruned))
+	}
+}
@@ -0,0 +1,153 @@
+package harvester
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/system"
+)
+
+// budgetEntry represents a selected game competing for storage budget
+type budgetEntry struct {
+	system *system.System
+	game   *rom.Game
+
+	// selected roms size
+	size int64
+
+	// position in include list, -1 if not listed
+	listRank int
+
+	// best preferred region index
+	regionRank int
+
+	// system weight
+	weight int
+
+	// copies of that game in less preferred systems sharing the same output directory, tried in order when that game does not fit
+	alternates []*budgetEntry
+}
+
+// applyBudget leaves out selected games that don't fit in storage budgets. Games are added by priority: curated list order, then region rank, then system weight. Games that don't fit are left out, but smaller games with lower priority may still fit. A game selected in several systems of given groups only counts once: the copy of the preferred system competes for budget, and other copies are only used when it does not fit.
+func (h *Harvester) applyBudget(groups []*system.Group) {
+	entries := []*budgetEntry{}
+
+	for _, group := range groups {
+		// entry of each game in the preferred system that selects it, indexed by normalized name
+		owners := map[string]*budgetEntry{}
+
+		for _, s := range group.Systems {
+			s.PlanRoms()
+
+			rk := rom.RankingFor(s.Options)
+
+			for key, g := range s.Games {
+				if len(g.Selected) == 0 {
+					continue
+				}
+
+				regionRank, _ := rk.RegionMatch(g.Selected[0])
+
+				e := &budgetEntry{
+					system:     s,
+					game:       g,
+					size:       system.GameSize(g),
+					listRank:   s.ListRank(g),
+					regionRank: regionRank,
+					weight:     s.Weight(),
+				}
+
+				if owner := owners[key]; owner != nil {
+					// dropped by deduplication, unless the owner copy does not fit
+					owner.alternates = append(owner.alternates, e)
+					continue
+				}
+
+				owners[key] = e
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	sort.Sort(entriesByPriority(entries))
+
+	var total int64
+	used := map[*system.System]int64{}
+
+	fits := func(e *budgetEntry) bool {
+		if (h.Options.MaxSize > 0) && (total+e.size > h.Options.MaxSize) {
+			return false
+		}
+
+		if max := e.system.MaxSize(); (max > 0) && (used[e.system]+e.size > max) {
+			return false
+		}
+
+		return true
+	}
+
+	for _, e := range entries {
+		for _, c := range append([]*budgetEntry{e}, e.alternates...) {
+			if fits(c) {
+				// copies in less preferred systems are dropped by deduplication
+				total += c.size
+				used[c.system] += c.size
+				break
+			}
+
+			if h.Options.Debug {
+				fmt.Printf("[%s] Left out '%s' by storage budget (%s)\n", c.system.Infos.Name, c.game.Name, core.FormatSize(c.size))
+			}
+
+			c.system.LeaveOut(c.game)
+		}
+	}
+}
+
+// entriesByPriority sorts budget entries by priority
+type entriesByPriority []*budgetEntry
+
+// Implements sort.Interface
+func (a entriesByPriority) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a entriesByPriority) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a entriesByPriority) Less(i, j int) bool {
+	e1, e2 := a[i], a[j]
+
+	// listed games first, in list order
+	if e1.listRank != e2.listRank {
+		if (e1.listRank < 0) || (e2.listRank < 0) {
+			return e2.listRank < 0
+		}
+
+		return e1.listRank < e2.listRank
+	}
+
+	if e1.regionRank != e2.regionRank {
+		return e1.regionRank < e2.regionRank
+	}
+
+	if e1.weight != e2.weight {
+		return e1.weight > e2.weight
+	}
+
+	// smaller games first
+	if e1.size != e2.size {
+		return e1.size < e2.size
+	}
+
+	if e1.system.Infos.FullName() != e2.system.Infos.FullName() {
+		return e1.system.Infos.FullName() < e2.system.Infos.FullName()
+	}
+
+	return e1.game.Name < e2.game.Name
+}
@@ -0,0 +1,118 @@
+package harvester
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/system"
+)
+
+// budgetGame represents a game of a budget test, selected in a system
+type budgetGame struct {
+	system   string
+	fileName string
+	size     int64
+}
+
+func TestApplyBudgetSharedDir(t *testing.T) {
+	const ws = "Bandai - WonderSwan"
+	const wsc = "Bandai - WonderSwan Color"
+
+	tests := []struct {
+		games    []budgetGame
+		maxSize  int64
+		selected []string
+		leftOut  []string
+	}{
+		// a game found in both systems only counts once, so that another game fits
+		{
+			[]budgetGame{
+				{wsc, "Gunpey (Japan).wsc", 100},
+				{ws, "Gunpey (Japan).ws", 100},
+				{ws, "Klonoa - Moonlight Museum (Japan).ws", 100},
+			},
+			200,
+			[]string{"Gunpey (Japan).wsc", "Klonoa - Moonlight Museum (Japan).ws"},
+			[]string{},
+		},
+		// the copy of the preferred system does not fit, the other one is selected
+		{
+			[]budgetGame{
+				{wsc, "Gunpey (Japan).wsc", 300},
+				{ws, "Gunpey (Japan).ws", 100},
+				{ws, "Klonoa - Moonlight Museum (Japan).ws", 100},
+			},
+			200,
+			[]string{"Gunpey (Japan).ws", "Klonoa - Moonlight Museum (Japan).ws"},
+			[]string{"Gunpey (Japan).wsc"},
+		},
+		// no copy fits
+		{
+			[]budgetGame{
+				{wsc, "Gunpey (Japan).wsc", 300},
+				{ws, "Gunpey (Japan).ws", 300},
+				{ws, "Klonoa - Moonlight Museum (Japan).ws", 100},
+			},
+			200,
+			[]string{"Klonoa - Moonlight Museum (Japan).ws"},
+			[]string{"Gunpey (Japan).ws", "Gunpey (Japan).wsc"},
+		},
+	}
+
+	for i, test := range tests {
+		options := core.NewOptions()
+		options.Regions = []string{"Japan"}
+		options.MaxSize = test.maxSize
+		options.Quiet = true
+
+		group := system.NewGroup("wswan", options)
+		systems := map[string]*system.System{}
+
+		for _, name := range []string{ws, wsc} {
+			systems[name] = system.New(system.SupportedSystemsMap[name], options)
+			group.AddSystem(systems[name])
+		}
+
+		for _, bg := range test.games {
+			r := rom.MustFill(bg.fileName)
+			r.Size = bg.size
+
+			g := rom.NewGame()
+			g.AddRom(r)
+
+			systems[bg.system].Games[rom.NormalizeTitle(r.Name)] = g
+		}
+
+		New(options).applyBudget([]*system.Group{group})
+		group.Dedup()
+
+		selected := []string{}
+		leftOut := []string{}
+
+		for _, s := range group.Systems {
+			for _, g := range s.Games {
+				for _, r := range g.Selected {
+					selected = append(selected, r.Filename)
+				}
+			}
+
+			for _, g := range s.LeftOut {
+				leftOut = append(leftOut, g.Roms[0].Filename)
+			}
+		}
+
+		sort.Strings(selected)
+		sort.Strings(leftOut)
+
+		if !reflect.DeepEqual(selected, test.selected) {
+			t.Errorf("Storage budget #%d failed, got selected %v but expected %v", i+1, selected, test.selected)
+		}
+
+		if !reflect.DeepEqual(leftOut, test.leftOut) {
+			t.Errorf("Storage budget #%d failed, got left out %v but expected %v", i+1, leftOut, test.leftOut)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+package harvester
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/skipper"
+	"github.com/aymerick/charette/system"
+)
+
+// ignoredExtensions holds the extensions of output files that are not roms
+var ignoredExtensions = map[string]bool{
+	".xml":   true,
+	".m3u":   true,
+	".srm":   true,
+	".sav":   true,
+	".state": true,
+	".txt":   true,
+	".png":   true,
+	".jpg":   true,
+	".ips":   true,
+	".bps":   true,
+	".ups":   true,
+}
+
+// Unwanted represents an output file that charette would not select anymore
+type Unwanted struct {
+	// file path
+	File string
+
+	// output directory name
+	Dir string
+
+	// explanation message
+	Reason string
+}
+
+// knownRoms holds rom and game names of a system found in input archives and DAT files
+type knownRoms struct {
+	// rom names without extension
+	roms map[string]bool
+
+	// normalized names of game archives
+	games map[string]bool
+}
+
+// Clean scans output directory, reports files that would not be selected anymore with current options, and moves them to quarantine directory if set
+func (h *Harvester) Clean() error {
+	unwanted, err := h.unwantedFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, u := range unwanted {
+		fmt.Printf("[%s] %s: %s\n", u.Dir, path.Base(u.File), u.Reason)
+
+		if h.Options.Quarantine != "" {
+			if err := h.quarantine(u); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("=============== TOTAL ===============\n")
+	fmt.Printf("Found %v unwanted files\n", len(unwanted))
+
+	return nil
+}
+
+// Verify scans output directory, reports files that would not be selected anymore with current options, corrupted files and roms with bad internal headers, then returns the number of reported files
+func (h *Harvester) Verify() (int, error) {
+	unwanted, err := h.unwantedFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	corrupted, err := h.corruptedFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	unwanted = append(unwanted, corrupted...)
+	sort.Sort(unwantedByFile(unwanted))
+
+	for _, u := range unwanted {
+		fmt.Printf("[%s] %s: %s\n", u.Dir, path.Base(u.File), u.Reason)
+	}
+
+	if !h.Options.Quiet {
+		fmt.Printf("=============== TOTAL ===============\n")
+		fmt.Printf("Found %v unwanted files (corrupted: %v)\n", len(unwanted), len(corrupted))
+	}
+
+	return len(unwanted), nil
+}
+
+// unwantedFiles returns all output files that would not be selected anymore
+func (h *Harvester) unwantedFiles() ([]*Unwanted, error) {
+	result := []*Unwanted{}
+
+	if h.Options.Debug {
+		fmt.Printf("Scaning output dir: %s\n", h.Options.Output)
+	}
+
+	if err := h.loadOverrides(); err != nil {
+		return result, err
+	}
+
+	if err := h.loadLists(); err != nil {
+		return result, err
+	}
+
+	if err := h.loadSkippers(); err != nil {
+		return result, err
+	}
+
+	if err := h.loadPatches(); err != nil {
+		return result, err
+	}
+
+	known, err := h.scanKnownRoms()
+	if err != nil {
+		return result, err
+	}
+
+	dirs, err := h.systemDirs()
+	if err != nil {
+		return result, err
+	}
+
+	for _, dir := range dirs {
+		files, err := h.cleanDir(dir, known)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, files...)
+	}
+
+	return result, nil
+}
+
+// corruptedFiles returns all zip files in output directory that can't be read, and all roms with bad internal headers
+func (h *Harvester) corruptedFiles() ([]*Unwanted, error) {
+	result := []*Unwanted{}
+
+	dirs, err := h.systemDirs()
+	if err != nil {
+		return result, err
+	}
+
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(path.Join(h.Options.Output, dir))
+		if err != nil {
+			return result, err
+		}
+
+		names := system.DirNames(dir)
+
+		for _, file := range files {
+			ext := strings.ToLower(filepath.Ext(file.Name()))
+			if file.IsDir() || ignoredExtensions[ext] || system.IsSaveFile(file.Name()) {
+				continue
+			}
+
+			filePath := path.Join(h.Options.Output, dir, file.Name())
+
+			if ext == ".zip" {
+				if err := helpers.CheckZip(filePath); err != nil {
+					result = append(result, &Unwanted{filePath, dir, fmt.Sprintf("Corrupted: %v", err)})
+					continue
+				}
+			}
+
+			check, err := system.CheckRom(rom.New(filePath), names, h.Options)
+			if err != nil {
+				return result, err
+			}
+
+			if (check != nil) && (len(check.Issues) > 0) {
+				result = append(result, &Unwanted{filePath, dir, fmt.Sprintf("Bad header: %s", strings.Join(check.Issues, ", "))})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// systemDirs returns the names of system directories found in output directory
+func (h *Harvester) systemDirs() ([]string, error) {
+	result := []string{}
+
+	dirs, err := ioutil.ReadDir(h.Options.Output)
+	if err != nil {
+		return result, err
+	}
+
+	for _, dir := range dirs {
+		if dir.IsDir() && isSystemDir(dir.Name()) {
+			result = append(result, dir.Name())
+		}
+	}
+
+	return result, nil
+}
+
+// cleanDir returns unwanted files in given output directory. In a directory shared by several systems, roms are ranked separately for each system, then a game selected in several systems is only kept in the preferred one, like when harvesting.
+func (h *Harvester) cleanDir(dir string, known map[string]*knownRoms) ([]*Unwanted, error) {
+	result := []*Unwanted{}
+	dirPath := path.Join(h.Options.Output, dir)
+
+	files, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return result, err
+	}
+
+	group := h.dirGroup(dir)
+
+	// games indexed by system full name, then by normalized name
+	games := map[string]map[string]*rom.Game{}
+
+	names := system.DirNames(dir)
+	detector := h.dirSkipper(dir)
+
+	bases := map[string]bool{}
+	for _, file := range files {
+		bases[helpers.FileBase(file.Name())] = true
+	}
+
+	// patched roms written next to original ones, indexed by original rom name
+	patched := map[string][]string{}
+
+	for _, file := range files {
+		if file.IsDir() || ignoredExtensions[strings.ToLower(filepath.Ext(file.Name()))] || system.IsSaveFile(file.Name()) {
+			continue
+		}
+
+		if original := patchedOriginal(helpers.FileBase(file.Name())); bases[original] {
+			// patched rom written next to the original one, it follows that rom
+			patched[original] = append(patched[original], path.Join(dirPath, file.Name()))
+			continue
+		}
+
+		filePath := path.Join(dirPath, file.Name())
+
+		r := rom.New(filePath)
+		if err := r.Fill(); err != nil {
+			return result, err
+		}
+
+		r.Skipper = detector
+
+		if patches := h.Patches.Find(helpers.FileBase(file.Name()), nil); len(patches) > 0 {
+			r.Patch = patches[0]
+		}
+
+		systems := romSystems(group, known, r)
+		if len(systems) == 0 {
+			result = append(result, &Unwanted{filePath, dir, "Unknown to source archives and DATs"})
+			continue
+		}
+
+		if skip, msg := system.OverrideSkip(r, h.Options, h.Overrides.Find(names, r.Name)); skip {
+			result = append(result, &Unwanted{filePath, dir, msg})
+			continue
+		}
+
+		key := rom.NormalizeTitle(r.Name)
+
+		for _, s := range systems {
+			fullName := s.Infos.FullName()
+			if games[fullName] == nil {
+				games[fullName] = map[string]*rom.Game{}
+			}
+
+			if games[fullName][key] == nil {
+				games[fullName][key] = rom.NewGame()
+			}
+
+			games[fullName][key].AddRom(r)
+		}
+	}
+
+	// a rom is unwanted if no system keeps it, with the reason given by the preferred system
+	wanted := map[*rom.Rom]bool{}
+	reasons := map[*rom.Rom]string{}
+
+	reject := func(r *rom.Rom, reason string) {
+		if reasons[r] == "" {
+			reasons[r] = reason
+		}
+	}
+
+	// system that selected each game, indexed by normalized name
+	selected := map[string]string{}
+
+	for _, s := range group.Systems {
+		systemNames := s.Names()
+
+		for key, g := range games[s.Infos.FullName()] {
+			if skip, msg := system.FilterGame(g, h.Include, h.Exclude, systemNames); skip {
+				for _, r := range g.Roms {
+					reject(r, msg)
+				}
+
+				continue
+			}
+
+			if other := selected[key]; other != "" {
+				for _, r := range g.Roms {
+					reject(r, fmt.Sprintf("Already selected from %s", other))
+				}
+
+				continue
+			}
+
+			best := system.OverrideBestRoms(g, h.Options, h.Overrides.Find(systemNames, g.Roms[0].Name))
+			selected[key] = s.Infos.FullName()
+
+			for _, r := range g.Roms {
+				if romIn(best, r) {
+					wanted[r] = true
+				} else {
+					reject(r, fmt.Sprintf("Superseded by '%s'", best[0].Filename))
+				}
+			}
+		}
+	}
+
+	for r, reason := range reasons {
+		if !wanted[r] {
+			result = append(result, &Unwanted{r.File, dir, reason})
+		}
+	}
+
+	// patched copies of unwanted roms are unwanted too
+	for _, u := range result {
+		for _, filePath := range patched[helpers.FileBase(u.File)] {
+			result = append(result, &Unwanted{filePath, dir, fmt.Sprintf("Patched copy of unwanted '%s'", path.Base(u.File))})
+		}
+	}
+
+	sort.Sort(unwantedByFile(result))
+
+	return result, nil
+}
+
+// dirGroup returns the group of systems sharing given output directory, sorted by preference
+func (h *Harvester) dirGroup(dir string) *system.Group {
+	result := system.NewGroup(dir, h.Options)
+
+	for _, infos := range system.SupportedSystems {
+		if infos.Dir == dir {
+			result.AddSystem(system.New(infos, h.Options))
+		}
+	}
+
+	return result
+}
+
+// romSystems returns the systems of given group which source archives or DATs contain given rom. If none of these systems is known, the rom is attributed to the preferred system.
+func romSystems(group *system.Group, known map[string]*knownRoms, r *rom.Rom) []*system.System {
+	result := []*system.System{}
+	found := false
+
+	for _, s := range group.Systems {
+		k := known[s.Infos.FullName()]
+		if k == nil {
+			continue
+		}
+
+		found = true
+
+		if k.roms[helpers.FileBase(r.Filename)] || k.games[rom.NormalizeTitle(r.Name)] {
+			result = append(result, s)
+		}
+	}
+
+	if !found {
+		return group.Systems[:1]
+	}
+
+	return result
+}
+
+// quarantine moves given unwanted file to quarantine directory
+func (h *Harvester) quarantine(u *Unwanted) error {
+	dir := path.Join(h.Options.Quarantine, u.Dir)
+	if err := os.MkdirAll(dir, 0777); (err != nil) && (err != os.ErrExist) {
+		return err
+	}
+
+	if h.Options.Debug {
+		fmt.Printf("Moving '%s' into: %s\n", u.File, dir)
+	}
+
+	return os.Rename(u.File, path.Join(dir, path.Base(u.File)))
+}
+
+// scanKnownRoms lists roms in input archives and DAT files, indexed by system full name
+func (h *Harvester) scanKnownRoms() (map[string]*knownRoms, error) {
+	result := map[string]*knownRoms{}
+
+	get := func(infos system.Infos) *knownRoms {
+		if result[infos.FullName()] == nil {
+			result[infos.FullName()] = &knownRoms{roms: map[string]bool{}, games: map[string]bool{}}
+		}
+
+		return result[infos.FullName()]
+	}
+
+	// input archives
+	if h.Options.Input != "" {
+		systems, err := h.scanArchives(h.Options.Input)
+		if err != nil {
+			return result, err
+		}
+
+		for infos, archives := range systems {
+			known := get(infos)
+
+			for _, archive := range archives {
+				entries, err := helpers.ListArchive(archive)
+				if err != nil {
+					return result, err
+				}
+
+				for _, entry := range entries {
+					if filepath.Ext(entry.Path) == ".7z" {
+						// archive of a specific game
+						name, _ := rom.NameAndRegions(path.Base(entry.Path))
+						known.games[rom.NormalizeTitle(name)] = true
+					} else {
+						known.roms[helpers.FileBase(entry.Path)] = true
+					}
+				}
+			}
+		}
+	}
+
+	// DAT files
+	dats, err := dat.LoadAll(h.Options.Dats)
+	if err != nil {
+		return result, err
+	}
+
+	for _, d := range dats {
+		infos, found := system.InfosForName(d.Header.Name)
+		if !found {
+			fmt.Printf("WARN: Unsupported system in DAT: %s\n", d.File)
+			continue
+		}
+
+		known := get(infos)
+
+		for _, g := range d.Games {
+			known.roms[g.Name] = true
+
+			for _, r := range g.Roms {
+				known.roms[helpers.FileBase(r.Name)] = true
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// dirSkipper returns the header skipper of systems in given output directory, or nil if none
+func (h *Harvester) dirSkipper(dir string) *skipper.Detector {
+	for _, infos := range system.SupportedSystems {
+		if infos.Dir != dir {
+			continue
+		}
+
+		if result := system.FindSkipper(infos, h.Skippers, system.SystemDats(infos, h.dats)); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// patchedOriginal returns the original rom name of given patched rom name written next to it, eg: "Gunpey (Japan)" for "Gunpey (Japan) [T-En]", or an empty string if that is not a patched rom name
+func patchedOriginal(name string) string {
+	if strings.HasSuffix(name, " (Patched)") {
+		return strings.TrimSuffix(name, " (Patched)")
+	}
+
+	if i := strings.Index(name, " ["); i > 0 {
+		return name[:i]
+	}
+
+	return ""
+}
+
+// isSystemDir returns true if given directory name is a system output directory
+func isSystemDir(dir string) bool {
+	for _, infos := range system.SupportedSystems {
+		if infos.Dir == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// romIn returns true if given rom is in given list
+func romIn(roms []*rom.Rom, r *rom.Rom) bool {
+	for _, v := range roms {
+		if v == r {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unwantedByFile sorts unwanted files by path
+type unwantedByFile []*Unwanted
+
+// Implements sort.Interface
+func (a unwantedByFile) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a unwantedByFile) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a unwantedByFile) Less(i, j int) bool {
+	return a[i].File < a[j].File
+}
@@ -0,0 +1,99 @@
+package harvester
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+)
+
+// newKnownRoms returns known roms with given rom names
+func newKnownRoms(names ...string) *knownRoms {
+	result := &knownRoms{roms: map[string]bool{}, games: map[string]bool{}}
+	for _, name := range names {
+		result.roms[name] = true
+	}
+
+	return result
+}
+
+func TestCleanDirSharedSystems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-harvester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"Gunpey (Japan).ws",
+		"Gunpey (Japan) (Rev 1).wsc",
+		"Klonoa - Moonlight Museum (Japan).ws",
+		"Klonoa - Moonlight Museum (Japan) [T-En].ws",
+		"Klonoa - Moonlight Museum (Japan) (Rev 1).ws",
+		"Riviera (Japan).wsc",
+		"Unknown Game (Japan).wsc",
+	}
+
+	os.MkdirAll(path.Join(dir, "wswan"), 0777)
+
+	for _, file := range files {
+		if err := ioutil.WriteFile(path.Join(dir, "wswan", file), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	known := map[string]*knownRoms{
+		"Bandai - WonderSwan":       newKnownRoms("Gunpey (Japan)", "Klonoa - Moonlight Museum (Japan)", "Klonoa - Moonlight Museum (Japan) (Rev 1)"),
+		"Bandai - WonderSwan Color": newKnownRoms("Gunpey (Japan) (Rev 1)", "Riviera (Japan)"),
+	}
+
+	tests := []struct {
+		preferred []string
+		expected  map[string]string
+	}{
+		// default preferences: WonderSwan Color first
+		{
+			nil,
+			map[string]string{
+				"Gunpey (Japan).ws":                           "Already selected from Bandai - WonderSwan Color",
+				"Klonoa - Moonlight Museum (Japan).ws":        "Superseded by 'Klonoa - Moonlight Museum (Japan) (Rev 1).ws'",
+				"Klonoa - Moonlight Museum (Japan) [T-En].ws": "Patched copy of unwanted 'Klonoa - Moonlight Museum (Japan).ws'",
+				"Unknown Game (Japan).wsc":                    "Unknown to source archives and DATs",
+			},
+		},
+		// the preferred system wins, even with an older revision
+		{
+			[]string{"Bandai - WonderSwan"},
+			map[string]string{
+				"Gunpey (Japan) (Rev 1).wsc":                  "Already selected from Bandai - WonderSwan",
+				"Klonoa - Moonlight Museum (Japan).ws":        "Superseded by 'Klonoa - Moonlight Museum (Japan) (Rev 1).ws'",
+				"Klonoa - Moonlight Museum (Japan) [T-En].ws": "Patched copy of unwanted 'Klonoa - Moonlight Museum (Japan).ws'",
+				"Unknown Game (Japan).wsc":                    "Unknown to source archives and DATs",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		options := core.NewOptions()
+		options.Output = dir
+		options.Regions = []string{"Japan"}
+		options.PreferredSystems = test.preferred
+
+		unwanted, err := New(options).cleanDir("wswan", known)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := map[string]string{}
+		for _, u := range unwanted {
+			result[path.Base(u.File)] = u.Reason
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Failed to clean shared directory with preferred systems %v\n\tgot     : %v\n\texpected: %v", test.preferred, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package harvester
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/system"
+)
+
+// runGame represents the selection of a game in a run
+type runGame struct {
+	// system full name
+	System string
+
+	// game name
+	Name string
+
+	// selected rom file name, empty if none
+	Rom string
+
+	// CRC32 of selected rom, empty if unknown
+	CRC string
+}
+
+// key returns the system and normalized game name
+func (g *runGame) key() string {
+	return g.System + "/" + rom.NormalizeTitle(g.Name)
+}
+
+// Diff compares two runs and displays added, removed and renamed games, and games which selected rom changes. Each run is either a no-intro archive or extracted directory, or a CSV report written by the report command.
+func (h *Harvester) Diff(oldPath string, newPath string) error {
+	oldRun, err := h.loadRun(oldPath)
+	if err != nil {
+		return err
+	}
+
+	newRun, err := h.loadRun(newPath)
+	if err != nil {
+		return err
+	}
+
+	changes := diffRuns(oldRun, newRun, h.changeRule)
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if !h.Options.Quiet {
+		fmt.Printf("=============== TOTAL ===============\n")
+		fmt.Printf("Found %v changes\n", len(changes))
+	}
+
+	return nil
+}
+
+// diffRuns returns the sorted list of added, removed, renamed and changed games between given runs. Given rule function returns the ranking rule explanation of a selected rom change.
+func diffRuns(oldRun map[string]*runGame, newRun map[string]*runGame, rule func(string, string) string) []string {
+	added := []*runGame{}
+	removed := []*runGame{}
+	changes := []string{}
+
+	for _, key := range runKeys(newRun) {
+		if oldRun[key] == nil {
+			added = append(added, newRun[key])
+		}
+	}
+
+	for _, key := range runKeys(oldRun) {
+		old := oldRun[key]
+		g := newRun[key]
+		if g == nil {
+			removed = append(removed, old)
+			continue
+		}
+
+		if g.Rom != old.Rom {
+			changes = append(changes, fmt.Sprintf("[%s] Changed: %s -> %s%s", g.System, orDash(old.Rom), orDash(g.Rom), rule(old.Rom, g.Rom)))
+		}
+	}
+
+	// a game removed and added with the same selected rom CRC32 was renamed
+	renamed := map[*runGame]*runGame{}
+
+	for _, old := range removed {
+		for _, g := range added {
+			if (old.CRC != "") && (old.CRC == g.CRC) && (old.System == g.System) && (renamed[g] == nil) {
+				renamed[g] = old
+				renamed[old] = g
+				changes = append(changes, fmt.Sprintf("[%s] Renamed: %s -> %s", g.System, old.Name, g.Name))
+				break
+			}
+		}
+	}
+
+	for _, g := range added {
+		if renamed[g] == nil {
+			changes = append(changes, fmt.Sprintf("[%s] Added: %s", g.System, g.Name))
+		}
+	}
+
+	for _, old := range removed {
+		if renamed[old] == nil {
+			changes = append(changes, fmt.Sprintf("[%s] Removed: %s", old.System, old.Name))
+		}
+	}
+
+	sort.Strings(changes)
+
+	return changes
+}
+
+// runKeys returns the sorted keys of given run
+func runKeys(run map[string]*runGame) []string {
+	result := []string{}
+	for key := range run {
+		result = append(result, key)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// changeRule returns the ranking rule that makes given new rom win over given old rom with current options, eg: " (decided by: version)", or an empty string if unknown
+func (h *Harvester) changeRule(oldRom string, newRom string) string {
+	if (oldRom == "") || (newRom == "") {
+		return ""
+	}
+
+	g := rom.NewGame()
+
+	for _, fileName := range []string{newRom, oldRom} {
+		r := rom.New(fileName)
+		if err := r.Fill(); err != nil {
+			return ""
+		}
+
+		g.AddRom(r)
+	}
+
+	better, rule := g.NewRankedSort(rom.RankingFor(h.Options)).Compare(g.Roms[0], g.Roms[1])
+	if !better || (rule == rom.RuleNone) {
+		return ""
+	}
+
+	return fmt.Sprintf(" (decided by: %s)", rule)
+}
+
+// loadRun returns the games selection of given run, indexed by system and normalized game name
+func (h *Harvester) loadRun(input string) (map[string]*runGame, error) {
+	if strings.ToLower(filepath.Ext(input)) == ".csv" {
+		return readReport(input)
+	}
+
+	return h.planRun(input)
+}
+
+// planRun selects roms of given no-intro archive or extracted directory without moving them, and returns the games selection
+func (h *Harvester) planRun(input string) (map[string]*runGame, error) {
+	result := map[string]*runGame{}
+
+	options := *h.Options
+	options.Input = input
+	options.DryRun = true
+	options.Quiet = true
+	options.Report = true
+	options.ExportDats = ""
+
+	run := New(&options)
+
+	if err := run.load(); err != nil {
+		return result, err
+	}
+
+	systems, err := run.scanArchives(input)
+	if err != nil {
+		return result, err
+	}
+
+	if len(systems) == 0 {
+		return result, fmt.Errorf("No no-intro archive found: %s", input)
+	}
+
+	for _, group := range run.groupSystems(systems) {
+		for _, s := range group.Systems {
+			if err := run.processSystemArchives(s, systems[s.Infos]); err != nil {
+				return result, err
+			}
+		}
+
+		group.Dedup()
+
+		for _, s := range group.Systems {
+			if err := s.SelectRoms(); err != nil {
+				return result, err
+			}
+
+			collectRun(s, result)
+		}
+	}
+
+	return result, nil
+}
+
+// collectRun adds the games selection of given system to given run
+func collectRun(s *system.System, run map[string]*runGame) {
+	for _, entry := range s.Report() {
+		if entry.Reason == system.ReasonMissing {
+			continue
+		}
+
+		g := &runGame{System: s.Infos.FullName(), Name: entry.Name, Rom: entry.Rom, CRC: entry.CRC}
+		run[g.key()] = g
+	}
+}
+
+// readReport returns the games selection of given CSV report
+func readReport(filePath string) (map[string]*runGame, error) {
+	result := map[string]*runGame{}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	// columns indexes
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+
+	for _, name := range []string{"system", "game", "rom"} {
+		if _, ok := columns[name]; !ok {
+			return result, fmt.Errorf("%s: missing '%s' column", filePath, name)
+		}
+	}
+
+	value := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && (i < len(record)) {
+			return record[i]
+		}
+
+		return ""
+	}
+
+	for _, record := range records[1:] {
+		if value(record, "reason") == system.ReasonMissing {
+			// game from DAT files, not in the set
+			continue
+		}
+
+		g := &runGame{System: value(record, "system"), Name: value(record, "game"), Rom: value(record, "rom"), CRC: value(record, "crc")}
+		result[g.key()] = g
+	}
+
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package harvester
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/system"
+)
+
+// newRun returns a run with given games
+func newRun(games ...*runGame) map[string]*runGame {
+	result := map[string]*runGame{}
+	for _, g := range games {
+		result[g.key()] = g
+	}
+
+	return result
+}
+
+func TestDiffRuns(t *testing.T) {
+	const gb = "Nintendo - Game Boy"
+	const nes = "Nintendo - Nintendo Entertainment System"
+
+	options := core.NewOptions()
+	options.Regions = []string{"Europe", "USA", "Japan"}
+
+	h := New(options)
+
+	tests := []struct {
+		old      map[string]*runGame
+		new      map[string]*runGame
+		expected []string
+	}{
+		// same selection
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}),
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}),
+			[]string{},
+		},
+		// added and removed games
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}, &runGame{gb, "Alleyway", "Alleyway (World).gb", "11111111"}),
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}, &runGame{gb, "Kwirk", "Kwirk (USA).gb", "22222222"}),
+			[]string{"[Nintendo - Game Boy] Added: Kwirk", "[Nintendo - Game Boy] Removed: Alleyway"},
+		},
+		// same game name in another system
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}),
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}, &runGame{nes, "Tetris", "Tetris (USA).nes", "33333333"}),
+			[]string{"[Nintendo - Nintendo Entertainment System] Added: Tetris"},
+		},
+		// renamed game, with the same selected rom CRC32
+		{
+			newRun(&runGame{gb, "Pokemon - Red Version", "Pokemon - Red Version (USA, Europe).gb", "9F7FDD53"}),
+			newRun(&runGame{gb, "Pokemon - Version Rouge", "Pokemon - Version Rouge (France).gb", "9F7FDD53"}),
+			[]string{"[Nintendo - Game Boy] Renamed: Pokemon - Red Version -> Pokemon - Version Rouge"},
+		},
+		// same CRC32 in another system is not a rename
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}),
+			newRun(&runGame{nes, "Tetris 2", "Tetris 2 (USA).nes", "46DF91AD"}),
+			[]string{"[Nintendo - Game Boy] Removed: Tetris", "[Nintendo - Nintendo Entertainment System] Added: Tetris 2"},
+		},
+		// unknown CRC32 is not a rename
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", ""}),
+			newRun(&runGame{gb, "Tetris DX", "Tetris DX (World).gb", ""}),
+			[]string{"[Nintendo - Game Boy] Added: Tetris DX", "[Nintendo - Game Boy] Removed: Tetris"},
+		},
+		// changed selected rom, with the deciding rule
+		{
+			newRun(&runGame{gb, "Tetris", "Tetris (World).gb", "46DF91AD"}),
+			newRun(&runGame{gb, "Tetris", "Tetris (World) (Rev 1).gb", "44444444"}),
+			[]string{"[Nintendo - Game Boy] Changed: Tetris (World).gb -> Tetris (World) (Rev 1).gb (decided by: version)"},
+		},
+		{
+			newRun(&runGame{gb, "Kwirk", "Kwirk (USA).gb", "22222222"}),
+			newRun(&runGame{gb, "Kwirk", "Kwirk (Europe).gb", "55555555"}),
+			[]string{"[Nintendo - Game Boy] Changed: Kwirk (USA).gb -> Kwirk (Europe).gb (decided by: region)"},
+		},
+		// game without selected rom anymore
+		{
+			newRun(&runGame{gb, "Kwirk", "Kwirk (USA).gb", "22222222"}),
+			newRun(&runGame{gb, "Kwirk", "", ""}),
+			[]string{"[Nintendo - Game Boy] Changed: Kwirk (USA).gb -> -"},
+		},
+	}
+
+	for i, test := range tests {
+		if result := diffRuns(test.old, test.new, h.changeRule); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Diff #%d failed\n\tgot     : %v\n\texpected: %v", i+1, result, test.expected)
+		}
+	}
+}
+
+func TestReadReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		csv      string
+		expected map[string]*runGame
+		valid    bool
+	}{
+		{
+			"system,game,status,rom,reason,crc\n" +
+				"Nintendo - Game Boy,Tetris,have,Tetris (World).gb,,46DF91AD\n" +
+				"Nintendo - Game Boy,Kwirk,miss,,Strict: [Japan],\n" +
+				"Nintendo - Game Boy,Alleyway,miss,,\"" + system.ReasonMissing + "\",\n",
+			newRun(
+				&runGame{"Nintendo - Game Boy", "Tetris", "Tetris (World).gb", "46DF91AD"},
+				&runGame{"Nintendo - Game Boy", "Kwirk", "", ""},
+			),
+			true,
+		},
+		// columns order does not matter, and crc column is optional
+		{
+			"rom,game,system\n" +
+				"\"Legend of Zelda, The (USA).gb\",\"Legend of Zelda, The\",Nintendo - Game Boy\n",
+			newRun(&runGame{"Nintendo - Game Boy", "Legend of Zelda, The", "Legend of Zelda, The (USA).gb", ""}),
+			true,
+		},
+		{
+			"",
+			newRun(),
+			true,
+		},
+		// missing rom column
+		{
+			"system,game,status\nNintendo - Game Boy,Tetris,have\n",
+			nil,
+			false,
+		},
+	}
+
+	for i, test := range tests {
+		filePath := path.Join(dir, "report.csv")
+		if err := ioutil.WriteFile(filePath, []byte(test.csv), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := readReport(filePath)
+		if (err == nil) != test.valid {
+			t.Errorf("Report #%d reading failed, got error: %v", i+1, err)
+			continue
+		}
+
+		if test.valid && !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Report #%d reading failed\n\tgot     : %v\n\texpected: %v", i+1, result, test.expected)
+		}
+	}
+}
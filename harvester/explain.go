@@ -0,0 +1,438 @@
+package harvester
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/system"
+)
+
+// Explain displays the ranking of given roms with current options, manual overrides and curated lists. Each argument is either a rom file name, or a game name that is searched in input archives. Roms found in input archives are ranked separately for each system, as harvest does.
+func (h *Harvester) Explain(args []string) error {
+	if err := h.loadOverrides(); err != nil {
+		return err
+	}
+
+	if err := h.loadLists(); err != nil {
+		return err
+	}
+
+	fileNames := []string{}
+	found := map[system.Infos][]string{}
+
+	for _, arg := range args {
+		if isRomFileName(arg) {
+			fileNames = append(fileNames, arg)
+			continue
+		}
+
+		roms, err := h.findGameRoms(arg)
+		if err != nil {
+			return err
+		}
+
+		if len(roms) == 0 {
+			fmt.Printf("Game not found in input archives: %s\n\n", arg)
+		}
+
+		for infos, names := range roms {
+			found[infos] = append(found[infos], names...)
+		}
+	}
+
+	// explicit file names belong to the system set with -system option, if any
+	var names []string
+	if infos, found := system.FindInfos(h.Options.System); (h.Options.System != "") && found {
+		names = infos.Names()
+	}
+
+	// only display parsing details for explicit file names
+	if err := h.explainRoms(fileNames, names, true); err != nil {
+		return err
+	}
+
+	for _, infos := range system.SortedInfos(found) {
+		fmt.Printf("##### %s\n\n", infos.FullName())
+
+		if err := h.explainRoms(found[infos], infos.Names(), false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// explainRoms groups given roms by game, then displays the ranking of each game in a system with given names. Parsing details of each rom are displayed first if details is true.
+func (h *Harvester) explainRoms(fileNames []string, systemNames []string, details bool) error {
+	games := map[string]*rom.Game{}
+	keys := []string{}
+
+	for _, fileName := range fileNames {
+		r := rom.New(fileName)
+		if err := r.Fill(); err != nil {
+			return err
+		}
+
+		if details {
+			h.explainRom(r)
+		}
+
+		key := rom.NormalizeTitle(r.Name)
+		if games[key] == nil {
+			games[key] = rom.NewGame()
+			keys = append(keys, key)
+		}
+
+		games[key].AddRom(r)
+	}
+
+	for _, key := range keys {
+		h.explainGame(games[key], systemNames)
+	}
+
+	return nil
+}
+
+// explainRom displays the informations extracted from given rom file name
+func (h *Harvester) explainRom(r *rom.Rom) {
+	fmt.Printf("%s\n", r.Filename)
+	fmt.Printf("   name: %s\n", r.Name)
+	fmt.Printf("   game: %s\n", rom.DisplayName(r.Name))
+	fmt.Printf("   regions: %s\n", strings.Join(r.Regions, ", "))
+
+	if len(r.Languages) > 0 {
+		fmt.Printf("   languages: %s\n", strings.Join(r.Languages, ", "))
+	}
+
+	if r.Version != "" {
+		fmt.Printf("   version: %s\n", r.Version)
+	}
+
+	if r.Video != "" {
+		fmt.Printf("   video: %s\n", r.Video)
+	}
+
+	if r.Part != "" {
+		fmt.Printf("   part: %s\n", r.Part)
+	}
+
+	if tags := altTags(r); len(tags) > 0 {
+		fmt.Printf("   tags: %s\n", strings.Join(tags, ", "))
+	}
+
+	fmt.Printf("\n")
+}
+
+// explainGame displays the full ranking of given game roms in a system with given names, honoring manual overrides and curated lists as harvest does
+func (h *Harvester) explainGame(g *rom.Game, systemNames []string) {
+	rk := rom.RankingFor(h.Options)
+	regions := rk.Regions
+
+	prefs := fmt.Sprintf("regions: %s", strings.Join(regions, ", "))
+	if rk.Scoring == core.RegionScoreCoverage {
+		prefs += ", scoring: coverage"
+	}
+
+	if rk.WorldAny {
+		prefs += ", world matches any"
+	}
+
+	if rk.Video != "" {
+		prefs += fmt.Sprintf(", video: %s", rk.Video)
+		if rk.VideoFirst {
+			prefs += " first"
+		}
+	}
+
+	fmt.Printf("=== %s (%s)\n", g.DisplayName(), prefs)
+
+	o := h.Overrides.Find(systemNames, g.Roms[0].Name)
+
+	// skip roms
+	candidates := rom.NewGame()
+	skipped := map[*rom.Rom]string{}
+
+	for _, r := range g.Roms {
+		if skip, msg := system.OverrideSkip(r, h.Options, o); skip {
+			skipped[r] = msg
+		} else {
+			candidates.AddRom(r)
+		}
+	}
+
+	// filter game with curated lists
+	if skip, msg := system.FilterGame(candidates, h.Include, h.Exclude, systemNames); skip {
+		for _, r := range candidates.Roms {
+			skipped[r] = msg
+		}
+
+		candidates = rom.NewGame()
+	}
+
+	// select winner before displaying the ranking, so that both use the same candidates order
+	var best []*rom.Rom
+	if len(candidates.Roms) > 0 {
+		best = system.OverrideBestRoms(candidates, h.Options, o)
+	}
+
+	// rank candidates, a stable sort keeps the order of already ranked candidates
+	gs := candidates.NewRankedSort(rk)
+	sort.Stable(gs)
+
+	if (len(best) > 0) && o.Pinned(best[0].Filename) {
+		// pinned release first
+		roms := append([]*rom.Rom{}, best...)
+		for _, r := range candidates.Roms {
+			if !romIn(best, r) {
+				roms = append(roms, r)
+			}
+		}
+
+		candidates.Roms = roms
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "#\tROM\tREGION\tVIDEO\tALT TAG\tVERSION\tDECISION\n")
+
+	for i, r := range candidates.Roms {
+		decision := ""
+
+		// previous candidate in ranking, nil for the first one
+		var prev *rom.Rom
+		if i > 0 {
+			prev = candidates.Roms[i-1]
+		}
+
+		switch {
+		case r == best[0]:
+			decision = "winner"
+			if o.Pinned(r.Filename) {
+				decision += " (pinned by override)"
+			}
+		case romIn(best, r):
+			decision = "selected with winner"
+		case prev == nil:
+			decision = "ranked first"
+		case o.Pinned(best[0].Filename) && romIn(best, prev):
+			decision = "not pinned"
+		default:
+			_, rule := gs.Compare(prev, r)
+			if rule == rom.RuleNone {
+				decision = fmt.Sprintf("ties with #%d", i)
+			} else {
+				decision = fmt.Sprintf("ranked after #%d by %s", i, rule)
+			}
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, r.Filename, regionRank(r, rk), orDash(r.Video), yesNo(r.HaveAltTag()), orDash(r.Version), decision)
+	}
+
+	for _, r := range g.Roms {
+		if msg, ok := skipped[r]; ok {
+			fmt.Fprintf(w, "-\t%s\t%s\t%s\t%s\t%s\tskipped: %s\n", r.Filename, regionRank(r, rk), orDash(r.Video), yesNo(r.HaveAltTag()), orDash(r.Version), msg)
+		}
+	}
+
+	w.Flush()
+
+	if len(candidates.Roms) == 0 {
+		fmt.Printf("=> No rom selected\n\n")
+		return
+	}
+
+	winner := best[0]
+
+	if o.Pinned(winner.Filename) {
+		fmt.Printf("=> Winner: %s (pinned by override)\n\n", winner.Filename)
+		return
+	}
+
+	if len(candidates.Roms) == 1 {
+		fmt.Printf("=> Winner: %s (only candidate)\n\n", winner.Filename)
+		return
+	}
+
+	// find first rom that is not part of the winner release
+	for _, r := range candidates.Roms {
+		if !romIn(best, r) {
+			_, rule := gs.Compare(winner, r)
+			if rule == rom.RuleNone {
+				rule = "input order"
+			}
+
+			fmt.Printf("=> Winner: %s (decided by: %s)\n\n", winner.Filename, rule)
+			return
+		}
+	}
+
+	fmt.Printf("=> Winner: %s (only release)\n\n", winner.Filename)
+}
+
+// findGameRoms searches roms of game with given name in input archives, and returns their file names indexed by system
+func (h *Harvester) findGameRoms(name string) (map[system.Infos][]string, error) {
+	result := map[system.Infos][]string{}
+	key := rom.NormalizeTitle(name)
+
+	systems, err := h.scanArchives(h.Options.Input)
+	if err != nil {
+		return result, err
+	}
+
+	for _, infos := range system.SortedInfos(systems) {
+		for _, archive := range systems[infos] {
+			entries, err := helpers.ListArchive(archive)
+			if err != nil {
+				return result, err
+			}
+
+			for _, entry := range entries {
+				fileName := path.Base(entry.Path)
+				if !isRomFileName(fileName) {
+					continue
+				}
+
+				gName, _ := rom.NameAndRegions(fileName)
+				if rom.NormalizeTitle(gName) != key {
+					continue
+				}
+
+				if filepath.Ext(fileName) != ".7z" {
+					result[infos] = append(result[infos], fileName)
+					continue
+				}
+
+				// archive of a specific game
+				fileNames, err := h.listGameArchive(archive, entry.Path)
+				if err != nil {
+					return result, err
+				}
+
+				result[infos] = append(result[infos], fileNames...)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// listGameArchive extracts given game archive from given system archive, and returns the roms file names it contains
+func (h *Harvester) listGameArchive(archive string, entry string) ([]string, error) {
+	result := []string{}
+
+	if helpers.IsDir(archive) {
+		// already extracted archive
+		return h.listGameArchiveFile(path.Join(archive, entry))
+	}
+
+	dir := path.Join(h.Options.Tmp, "explain")
+	defer os.RemoveAll(dir)
+
+	if err := helpers.ExecCmd("7z", []string{"e", archive, "-o" + dir, entry, "-y"}); err != nil {
+		return result, err
+	}
+
+	return h.listGameArchiveFile(path.Join(dir, path.Base(entry)))
+}
+
+// listGameArchiveFile returns the roms file names in given game archive
+func (h *Harvester) listGameArchiveFile(filePath string) ([]string, error) {
+	result := []string{}
+
+	entries, err := helpers.ListArchive(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	for _, e := range entries {
+		result = append(result, path.Base(e.Path))
+	}
+
+	return result, nil
+}
+
+// isRomFileName returns true if given string looks like a no-intro rom file name, and not like a game name
+func isRomFileName(str string) bool {
+	return strings.Contains(str, " (")
+}
+
+// regionRank returns the best region index of given rom, as displayed by explain
+func regionRank(r *rom.Rom, rk *rom.Ranking) string {
+	regions := rk.Regions
+
+	i, exact := rk.RegionMatch(r)
+	if i >= len(regions) {
+		return "none"
+	}
+
+	result := fmt.Sprintf("%d (%s", i+1, regions[i])
+	if !exact {
+		result += ", implied"
+	}
+
+	if rk.Scoring == core.RegionScoreCoverage {
+		result += fmt.Sprintf(", covers %d", rk.Coverage(r))
+	}
+
+	return result + ")"
+}
+
+// yesNo returns "yes" or "no"
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// orDash returns given string, or "-" if empty
+func orDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+
+	return str
+}
+
+// altTags returns the alternative version tags of given rom
+func altTags(r *rom.Rom) []string {
+	result := []string{}
+
+	if r.Bios {
+		result = append(result, "BIOS")
+	}
+
+	if r.Proto {
+		result = append(result, "Proto")
+	}
+
+	if r.Beta {
+		result = append(result, "Beta")
+	}
+
+	if r.Sample {
+		result = append(result, "Sample")
+	}
+
+	if r.Demo {
+		result = append(result, "Demo")
+	}
+
+	if r.Pirate {
+		result = append(result, "Pirate")
+	}
+
+	if r.Promo {
+		result = append(result, "Promo")
+	}
+
+	return result
+}
@@ -6,10 +6,20 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/cheggaaa/pb"
 
 	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/curated"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/gamelist"
+	"github.com/aymerick/charette/overrides"
+	"github.com/aymerick/charette/patch"
+	"github.com/aymerick/charette/skipper"
 	"github.com/aymerick/charette/system"
 )
 
@@ -20,6 +30,22 @@ type Harvester struct {
 
 	// systems found
 	Systems []*system.System
+
+	// manual overrides, nil if none
+	Overrides *overrides.List
+
+	// curated lists of games to include and to exclude, nil if none
+	Include *curated.List
+	Exclude *curated.List
+
+	// header skipper definitions
+	Skippers []*skipper.Detector
+
+	// patches library, nil if none
+	Patches *patch.Library
+
+	// DAT files, nil until loaded
+	dats []*dat.Dat
 }
 
 // New instanciates a new Harvester
@@ -35,17 +61,77 @@ func (h *Harvester) Run() error {
 		fmt.Printf("Scaning input dir: %s\n", h.Options.Input)
 	}
 
+	if err := h.load(); err != nil {
+		return err
+	}
+
 	// detect all no-intro archives
 	systems, err := h.scanArchives(h.Options.Input)
 	if err != nil {
 		return err
 	}
 
-	// process archives
-	for infos, archives := range systems {
-		s := h.addSystem(infos)
+	// with a storage budget, roms are moved once all systems have been processed
+	budget := h.Options.UseBudget()
+
+	// process archives, grouped by output directory
+	groups := h.groupSystems(systems)
+
+	for _, group := range groups {
+		for _, s := range group.Systems {
+			if err := h.processSystemArchives(s, systems[s.Infos]); err != nil {
+				return err
+			}
+		}
+
+		if !budget {
+			// skip games selected in several systems
+			group.Dedup()
+
+			if err := h.selectGroupRoms(group); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// keep planned roms only, until storage budget is applied
+		for _, s := range group.Systems {
+			if err := s.Release(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if budget {
+		h.applyBudget(groups)
 
-		if err := h.processSystemArchives(s, archives); err != nil {
+		for _, group := range groups {
+			// skip games selected in several systems, once games left out by storage budget are known
+			group.Dedup()
+
+			if err := h.selectGroupRoms(group); err != nil {
+				return err
+			}
+		}
+	}
+
+	// export selection
+	if h.Options.ExportDats != "" {
+		if err := h.exportDats(); err != nil {
+			return err
+		}
+	}
+
+	if h.Options.Report {
+		if err := h.printReport(); err != nil {
+			return err
+		}
+	}
+
+	// write gamelists
+	if h.Options.Gamelist && !h.Options.DryRun {
+		if err := h.writeGamelists(); err != nil {
 			return err
 		}
 	}
@@ -56,34 +142,407 @@ func (h *Harvester) Run() error {
 	return nil
 }
 
+// load loads manual overrides, curated lists, header skippers, patches and DAT files needed by options
+func (h *Harvester) load() error {
+	if err := h.loadOverrides(); err != nil {
+		return err
+	}
+
+	if err := h.loadLists(); err != nil {
+		return err
+	}
+
+	if err := h.loadSkippers(); err != nil {
+		return err
+	}
+
+	if err := h.loadPatches(); err != nil {
+		return err
+	}
+
+	// exported DATs hold hashes from DAT files, the completeness report lists games of DAT files, and patches keyed by CRC32 are looked up with DAT hashes
+	if (h.Options.ExportDats != "") || h.Options.Report || (h.Patches != nil) {
+		if _, err := h.loadDats(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadOverrides loads manual overrides file, if any
+func (h *Harvester) loadOverrides() error {
+	if (h.Options.Overrides == "") || (h.Overrides != nil) {
+		return nil
+	}
+
+	if h.Options.Debug {
+		fmt.Printf("Loading overrides: %s\n", h.Options.Overrides)
+	}
+
+	list, err := overrides.Load(h.Options.Overrides)
+	if err != nil {
+		return err
+	}
+
+	h.Overrides = list
+
+	return nil
+}
+
+// loadLists loads curated lists of games to include and to exclude, if any. Hashes in lists are resolved with DAT files.
+func (h *Harvester) loadLists() error {
+	if (h.Include != nil) || (h.Exclude != nil) {
+		return nil
+	}
+
+	var err error
+
+	if h.Options.IncludeList != "" {
+		if h.Include, err = curated.Load(h.Options.IncludeList); err != nil {
+			return err
+		}
+	}
+
+	if h.Options.ExcludeList != "" {
+		if h.Exclude, err = curated.Load(h.Options.ExcludeList); err != nil {
+			return err
+		}
+	}
+
+	if ((h.Include != nil) || (h.Exclude != nil)) && (len(h.Options.Dats) > 0) {
+		dats, err := h.loadDats()
+		if err != nil {
+			return err
+		}
+
+		h.Include.ResolveHashes(dats)
+		h.Exclude.ResolveHashes(dats)
+	}
+
+	return nil
+}
+
+// loadDats loads DAT files, once
+func (h *Harvester) loadDats() ([]*dat.Dat, error) {
+	if h.dats != nil {
+		return h.dats, nil
+	}
+
+	dats, err := dat.LoadAll(h.Options.Dats)
+	if err != nil {
+		return nil, err
+	}
+
+	h.dats = dats
+
+	return h.dats, nil
+}
+
+// loadSkippers loads header skipper definitions, if any
+func (h *Harvester) loadSkippers() error {
+	if (len(h.Options.HeaderSkippers) == 0) || (h.Skippers != nil) {
+		return nil
+	}
+
+	if h.Options.Debug {
+		fmt.Printf("Loading header skippers: %s\n", strings.Join(h.Options.HeaderSkippers, ", "))
+	}
+
+	detectors, err := skipper.LoadAll(h.Options.HeaderSkippers)
+	if err != nil {
+		return err
+	}
+
+	h.Skippers = detectors
+
+	// DATs reference header skippers, and hold headers to add
+	_, err = h.loadDats()
+
+	return err
+}
+
+// loadPatches scans patches directory, if any
+func (h *Harvester) loadPatches() error {
+	if (h.Options.Patches == "") || (h.Patches != nil) {
+		return nil
+	}
+
+	if h.Options.Debug {
+		fmt.Printf("Scaning patches dir: %s\n", h.Options.Patches)
+	}
+
+	library, err := patch.NewLibrary(h.Options.Patches)
+	if err != nil {
+		return err
+	}
+
+	h.Patches = library
+
+	return nil
+}
+
+// printUnmatched displays curated lists entries that matched no game
+func (h *Harvester) printUnmatched() {
+	lists := []*curated.List{h.Include, h.Exclude}
+
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+
+		if unmatched := l.Unmatched(); len(unmatched) > 0 {
+			fmt.Printf("Entries of %s that matched nothing:\n", l.File)
+
+			for _, e := range unmatched {
+				fmt.Printf("\t%s\n", e)
+			}
+		}
+	}
+}
+
+// printOverrides displays applied and unused overrides
+func (h *Harvester) printOverrides() {
+	if h.Overrides == nil {
+		return
+	}
+
+	fmt.Printf("Overrides:\n")
+
+	for _, o := range h.Overrides.Applied() {
+		fmt.Printf("\t%s: %s\n", o, strings.Join(o.Applied, ", "))
+	}
+
+	for _, o := range h.Overrides.Unused() {
+		fmt.Printf("\t%s: not applied\n", o)
+	}
+}
+
+// writeGamelists writes or merges a gamelist file into each output directory, with selected games from all systems sharing that directory
+func (h *Harvester) writeGamelists() error {
+	gamelists := map[string]*gamelist.Gamelist{}
+
+	for _, s := range h.Systems {
+		dir := s.OutputDir()
+
+		if gamelists[dir] == nil {
+			gl, err := gamelist.Load(path.Join(dir, gamelist.FileName))
+			if err != nil {
+				return err
+			}
+
+			// keep metadata of replaced roms
+			for _, sys := range h.Systems {
+				if sys.OutputDir() != dir {
+					continue
+				}
+
+				for oldName, newName := range sys.Renamed {
+					if gl.Rename(oldName, newName) && h.Options.Debug {
+						fmt.Printf("Renamed gamelist entry '%s' to: %s\n", oldName, newName)
+					}
+				}
+			}
+
+			// remove entries of deleted roms
+			for _, g := range gl.Prune(dir) {
+				if h.Options.Debug {
+					fmt.Printf("Pruned gamelist entry: %s\n", g.Path)
+				}
+			}
+
+			gamelists[dir] = gl
+		}
+
+		s.FillGamelist(gamelists[dir])
+	}
+
+	for dir, gl := range gamelists {
+		filePath := path.Join(dir, gamelist.FileName)
+
+		if h.Options.Debug {
+			fmt.Printf("Writing gamelist: %s\n", filePath)
+		}
+
+		if err := gl.Write(filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportDats writes a DAT file of selected roms for each system into export directory
+func (h *Harvester) exportDats() error {
+	if err := os.MkdirAll(h.Options.ExportDats, 0777); (err != nil) && (err != os.ErrExist) {
+		return err
+	}
+
+	for _, s := range h.Systems {
+		filePath := path.Join(h.Options.ExportDats, s.Infos.FullName()+" (1G1R).dat")
+
+		if !h.Options.Quiet {
+			fmt.Printf("[%s] Writing DAT: %s\n", s.Infos.Name, filePath)
+		}
+
+		if err := s.ExportDat().Write(filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printPlan displays roms that would be selected for given system
+func (h *Harvester) printPlan(s *system.System) {
+	names := []string{}
+	for _, g := range s.Games {
+		for _, r := range g.Selected {
+			if r.Patch != "" {
+				names = append(names, fmt.Sprintf("%s (patch: %s)", r.Filename, path.Base(r.Patch)))
+			} else {
+				names = append(names, r.Filename)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("[%s] %s\n", s.Infos.Name, name)
+	}
+}
+
+// Stats returns the processing statistics of all systems, sorted by system name
+func (h *Harvester) Stats() []*system.Stats {
+	systems := append([]*system.System{}, h.Systems...)
+	sort.Sort(systemsByName(systems))
+
+	result := []*system.Stats{}
+	for _, s := range systems {
+		result = append(result, s.Stats())
+	}
+
+	return result
+}
+
+// printStats displays the statistics table of all systems, then totals
 func (h *Harvester) printStats() {
 	processed := 0
 	skipped := 0
 	games := 0
+	filtered := 0
+	leftOut := 0
+	var size int64
+	reasons := map[string]int{}
 	regions := map[string]int{}
 
 	for _, s := range h.Systems {
 		processed += s.Processed
 		skipped += s.Skipped
-		games += len(s.Games)
+		games += s.SelectedGames()
+		filtered += s.Filtered
+		leftOut += len(s.LeftOut)
 
-		for region, nb := range s.RegionsStats {
-			regions[region] += nb
+		if h.Options.UseBudget() {
+			size += s.Size()
 		}
 	}
 
+	stats := h.Stats()
+
+	if len(stats) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "SYSTEM\tSEEN\tGROUPED\tSELECTED\tSKIPPED\tIN\tOUT\tEXTRACTING\tSELECTING\n")
+
+		for _, st := range stats {
+			nb := 0
+			for _, c := range st.Skipped {
+				nb += c.Count
+				reasons[c.Name] += c.Count
+			}
+
+			for _, c := range st.Regions {
+				regions[c.Name] += c.Count
+			}
+
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n", st.System, st.Seen, st.Grouped, st.Selected, nb, core.FormatSize(st.BytesIn), core.FormatSize(st.BytesOut), st.Extracting.Round(time.Millisecond), st.Selecting.Round(time.Millisecond))
+		}
+
+		w.Flush()
+	}
+
 	fmt.Printf("=============== TOTAL ===============\n")
 	fmt.Printf("Processed %v files (skipped: %v)\n", processed, skipped)
 	fmt.Printf("Selected %v games\n", games)
+
+	if filtered > 0 {
+		fmt.Printf("Left out %v games by curated lists\n", filtered)
+	}
+
+	if h.Options.UseBudget() {
+		fmt.Printf("Left out %v games by storage budget\n", leftOut)
+
+		if h.Options.MaxSize > 0 {
+			fmt.Printf("Storage: %s of %s\n", core.FormatSize(size), core.FormatSize(h.Options.MaxSize))
+		} else {
+			fmt.Printf("Storage: %s\n", core.FormatSize(size))
+		}
+	}
+
+	if len(reasons) > 0 {
+		fmt.Printf("Skip reasons:\n")
+
+		for _, c := range system.SortedCounts(reasons) {
+			fmt.Printf("\t%s: %d\n", c.Name, c.Count)
+		}
+	}
+
 	fmt.Printf("Regions:\n")
 
-	for region, nb := range regions {
-		fmt.Printf("\t%s: %d\n", region, nb)
+	for _, c := range system.SortedCounts(regions) {
+		fmt.Printf("\t%s: %d\n", c.Name, c.Count)
 	}
+
+	h.printUnmatched()
+	h.printOverrides()
+}
+
+// Scan returns a map of {System Infos} => [Archives paths] for all no-intro archives found in input directory
+func (h *Harvester) Scan() (map[system.Infos][]string, error) {
+	return h.scanArchives(h.Options.Input)
 }
 
-// scanArchives returns a map of {System Infos} => [Archives paths]
+// scanArchives returns a map of {System Infos} => [Archives paths]. Archives are sorted by set date, newest first, and only the newest set of each system is kept unless MergeSets option is set.
 func (h *Harvester) scanArchives(input string) (map[system.Infos][]string, error) {
+	result, err := h.scanInput(input)
+	if err != nil {
+		return result, err
+	}
+
+	for infos, archives := range result {
+		system.SortArchives(archives)
+
+		if h.Options.MergeSets {
+			continue
+		}
+
+		newest, older := system.NewestArchives(archives)
+
+		if !h.Options.Quiet {
+			for _, archive := range older {
+				fmt.Printf("[%s] Ignoring older set: %s\n", infos.Name, path.Base(archive))
+			}
+		}
+
+		result[infos] = newest
+	}
+
+	return result, nil
+}
+
+// scanInput returns a map of {System Infos} => [Archives paths] for all archives found in given input path
+func (h *Harvester) scanInput(input string) (map[system.Infos][]string, error) {
 	result := make(map[system.Infos][]string)
 
 	fileInfo, err := os.Stat(input)
@@ -91,9 +550,35 @@ func (h *Harvester) scanArchives(input string) (map[system.Infos][]string, error
 		return result, err
 	}
 
+	if h.Options.System != "" {
+		// input is a single archive or extracted directory of given system
+		infos, found := system.FindInfos(h.Options.System)
+		if !found {
+			return result, fmt.Errorf("Unknown system: %s", h.Options.System)
+		}
+
+		result[infos] = []string{input}
+
+		return result, nil
+	}
+
 	if fileInfo.IsDir() {
+		// already extracted archive
+		if infos, found := system.InfosForName(path.Base(input)); found {
+			result[infos] = []string{input}
+			return result, nil
+		}
+
 		// scan archives directory
-		return h.scanArchivesDir(input)
+		result, err = h.scanArchivesDir(input)
+		if (err == nil) && (len(result) == 0) && h.haveRomFiles(input) {
+			// misnamed extracted archive
+			if infos, found := h.detectSystem(input); found {
+				result[infos] = []string{input}
+			}
+		}
+
+		return result, err
 	}
 
 	// scan archive file
@@ -118,20 +603,37 @@ func (h *Harvester) scanArchivesDir(dirPath string) (map[system.Infos][]string,
 
 		if file.IsDir() {
 			// ignore /roms and /.~charette directories
-			if (filePath != path.Clean(h.Options.Output)) && (filePath != path.Clean(h.Options.Tmp)) {
-				// scan subdir
-				if h.Options.Debug {
-					fmt.Printf("Scaning subdir: %s\n", filePath)
-				}
+			if (filePath == path.Clean(h.Options.Output)) || (filePath == path.Clean(h.Options.Tmp)) {
+				continue
+			}
 
-				subArchives, err := h.scanArchivesDir(filePath)
-				if err != nil {
-					return result, nil
-				}
+			// already extracted archive
+			if infos, found := system.InfosForName(file.Name()); found {
+				result[infos] = append(result[infos], filePath)
+				continue
+			}
+
+			// scan subdir
+			if h.Options.Debug {
+				fmt.Printf("Scaning subdir: %s\n", filePath)
+			}
+
+			subArchives, err := h.scanArchivesDir(filePath)
+			if err != nil {
+				return result, nil
+			}
 
-				for infos, archives := range subArchives {
-					result[infos] = append(result[infos], archives...)
+			if (len(subArchives) == 0) && h.haveRomFiles(filePath) {
+				// misnamed extracted archive
+				if infos, found := h.detectSystem(filePath); found {
+					result[infos] = append(result[infos], filePath)
 				}
+
+				continue
+			}
+
+			for infos, archives := range subArchives {
+				result[infos] = append(result[infos], archives...)
 			}
 		} else {
 			if infos, found := h.scanArchiveFile(filePath); found {
@@ -143,28 +645,111 @@ func (h *Harvester) scanArchivesDir(dirPath string) (map[system.Infos][]string,
 	return result, nil
 }
 
+// scanArchiveFile returns system informations for given .7z or .zip archive, the second value returned is `false` if that is not a system archive
 func (h *Harvester) scanArchiveFile(filePath string) (system.Infos, bool) {
 	var result system.Infos
 	found := false
 
 	// scan archive
 	fileExt := filepath.Ext(filePath)
-	if fileExt == ".7z" {
+	if (fileExt == ".7z") || (fileExt == ".zip") {
 		result, found = system.InfosForArchive(filePath)
+
+		if !found && !system.HasRegionTag(path.Base(filePath)) {
+			// misnamed archive
+			result, found = h.detectSystem(filePath)
+		}
 	}
 
 	return result, found
 }
 
+// detectSystem guesses the system of given archive or directory from its contents, the second value returned is `false` if no system was found with enough confidence
+func (h *Harvester) detectSystem(filePath string) (system.Infos, bool) {
+	d, err := system.DetectArchive(filePath, h.Options.Tmp)
+	if err != nil {
+		fmt.Printf("[detect] %s: ERR: %v\n", path.Base(filePath), err)
+		return system.Infos{}, false
+	}
+
+	if d == nil {
+		if !h.Options.Quiet {
+			fmt.Printf("[detect] %s: unknown system, skipped\n", path.Base(filePath))
+		}
+
+		return system.Infos{}, false
+	}
+
+	if d.Confidence < system.MinConfidence {
+		if !h.Options.Quiet {
+			fmt.Printf("[detect] %s: maybe %s, skipped (set -system to process it)\n", path.Base(filePath), d)
+		}
+
+		return system.Infos{}, false
+	}
+
+	if !h.Options.Quiet {
+		fmt.Printf("[detect] %s: %s\n", path.Base(filePath), d)
+	}
+
+	return d.Infos, true
+}
+
+// haveRomFiles returns true if given directory directly contains files named like no-intro roms
+func (h *Harvester) haveRomFiles(dirPath string) bool {
+	files, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return false
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && system.HasRegionTag(file.Name()) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // addSystem registers a new system
 func (h *Harvester) addSystem(infos system.Infos) *system.System {
 	result := system.New(infos, h.Options)
+	result.Overrides = h.Overrides
+	result.Include = h.Include
+	result.Exclude = h.Exclude
+	result.Dats = system.SystemDats(infos, h.dats)
+	result.Skipper = system.FindSkipper(infos, h.Skippers, result.Dats)
+	result.Patches = h.Patches
+
+	if (result.Skipper != nil) && h.Options.Debug {
+		fmt.Printf("[%s] Header skipper: %s\n", infos.Name, result.Skipper.Name)
+	}
 
 	h.Systems = append(h.Systems, result)
 
 	return result
 }
 
+// groupSystems registers systems for given archives, and groups them by output directory
+func (h *Harvester) groupSystems(systems map[system.Infos][]string) []*system.Group {
+	result := []*system.Group{}
+	groups := map[string]*system.Group{}
+
+	for infos := range systems {
+		group := groups[infos.Dir]
+		if group == nil {
+			group = system.NewGroup(infos.Dir, h.Options)
+
+			groups[infos.Dir] = group
+			result = append(result, group)
+		}
+
+		group.AddSystem(h.addSystem(infos))
+	}
+
+	return result
+}
+
 // processSystemArchives processes archives for given system
 func (h *Harvester) processSystemArchives(s *system.System, archives []string) error {
 	var bar *pb.ProgressBar
@@ -189,7 +774,7 @@ func (h *Harvester) processSystemArchives(s *system.System, archives []string) e
 			bar.Increment()
 		}
 
-		if err := s.ProcessArchive(archive, h.Options.Output); err != nil {
+		if err := s.ProcessArchive(archive); err != nil {
 			return err
 		}
 	}
@@ -199,7 +784,95 @@ func (h *Harvester) processSystemArchives(s *system.System, archives []string) e
 		fmt.Printf("[%s] Processed %v files (skipped: %v)\n", s.Infos.Name, s.Processed, s.Skipped)
 	}
 
-	fmt.Printf("[%s] Selected %v games\n", s.Infos.Name, len(s.Games))
+	return nil
+}
+
+// selectGroupRoms moves selected roms of all systems in given group
+func (h *Harvester) selectGroupRoms(group *system.Group) error {
+	for _, s := range group.Systems {
+		if err := h.selectSystemRoms(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectSystemRoms moves selected roms of given system, once all archives have been processed
+func (h *Harvester) selectSystemRoms(s *system.System) error {
+	if err := s.SelectRoms(); err != nil {
+		return err
+	}
+
+	if s.Options.DryRun && !s.Options.Report {
+		h.printPlan(s)
+	}
+
+	fmt.Printf("[%s] Selected %v games\n", s.Infos.Name, s.SelectedGames())
+
+	if s.Options.UseBudget() {
+		if max := s.MaxSize(); max > 0 {
+			fmt.Printf("[%s] Storage: %s of %s\n", s.Infos.Name, core.FormatSize(s.Size()), core.FormatSize(max))
+		} else {
+			fmt.Printf("[%s] Storage: %s\n", s.Infos.Name, core.FormatSize(s.Size()))
+		}
+
+		if (len(s.LeftOut) > 0) && !s.Options.Quiet {
+			fmt.Printf("[%s] %v games left out by storage budget:\n", s.Infos.Name, len(s.LeftOut))
+
+			for _, g := range s.LeftOut {
+				fmt.Printf("\t%s\n", g.Name)
+			}
+		}
+	}
+
+	if (s.Options.MediaDir != "") && (len(s.MissingMedia) > 0) && !s.Options.Quiet {
+		fmt.Printf("[%s] %v games without media:\n", s.Infos.Name, len(s.MissingMedia))
+
+		for _, g := range s.MissingMedia {
+			fmt.Printf("\t%s\n", g.Name)
+		}
+	}
+
+	if (len(s.Patched) > 0) && !s.Options.Quiet {
+		sort.Strings(s.Patched)
+
+		fmt.Printf("[%s] %v roms patched:\n", s.Infos.Name, len(s.Patched))
+
+		for _, msg := range s.Patched {
+			fmt.Printf("\t%s\n", msg)
+		}
+	}
+
+	if (len(s.Unpaired) > 0) && !s.Options.Quiet {
+		sort.Strings(s.Unpaired)
+
+		fmt.Printf("[%s] %v patches not paired with selected roms:\n", s.Infos.Name, len(s.Unpaired))
+
+		for _, msg := range s.Unpaired {
+			fmt.Printf("\t%s\n", msg)
+		}
+	}
+
+	if (len(s.Migrated) > 0) && !s.Options.Quiet {
+		sort.Strings(s.Migrated)
+
+		fmt.Printf("[%s] %v roms replaced, saves migrated:\n", s.Infos.Name, len(s.Migrated))
+
+		for _, msg := range s.Migrated {
+			fmt.Printf("\t%s\n", msg)
+		}
+	}
+
+	if (len(s.HeaderIssues) > 0) && !s.Options.Quiet {
+		sort.Strings(s.HeaderIssues)
+
+		fmt.Printf("[%s] %v roms with header issues:\n", s.Infos.Name, len(s.HeaderIssues))
+
+		for _, msg := range s.HeaderIssues {
+			fmt.Printf("\t%s\n", msg)
+		}
+	}
 
 	return nil
 }
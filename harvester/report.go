@@ -0,0 +1,119 @@
+package harvester
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/system"
+)
+
+// reportHeader holds the columns of CSV report
+var reportHeader = []string{"system", "game", "status", "rom", "reason", "crc", "patched"}
+
+// printReport displays the completeness report of each system, and writes it to CSV file if set
+func (h *Harvester) printReport() error {
+	systems := append([]*system.System{}, h.Systems...)
+	sort.Sort(systemsByName(systems))
+
+	var records [][]string
+
+	for _, s := range systems {
+		entries := s.Report()
+
+		counts := map[string]int{}
+		for _, entry := range entries {
+			counts[entry.Status]++
+		}
+
+		fmt.Printf("=== %s: %d %s, %d %s, %d %s\n", s.Infos.FullName(), counts[system.StatusHave], system.StatusHave, counts[system.StatusFallback], system.StatusFallback, counts[system.StatusMiss], system.StatusMiss)
+
+		st := s.Stats()
+		fmt.Printf("Roms: %d seen, %d games, %d selected (%s in, %s out)\n", st.Seen, st.Grouped, st.Selected, core.FormatSize(st.BytesIn), core.FormatSize(st.BytesOut))
+
+		if len(st.Skipped) > 0 {
+			fmt.Printf("Skip reasons: %s\n", formatCounts(st.Skipped))
+		}
+
+		if len(st.Regions) > 0 {
+			fmt.Printf("Regions: %s\n", formatCounts(st.Regions))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "STATUS\tGAME\tROM\tREASON\n")
+
+		for _, entry := range entries {
+			romName := orDash(entry.Rom)
+			if entry.Patched != "" {
+				romName += fmt.Sprintf(" (patched: %s)", entry.Patched)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Status, entry.Name, romName, orDash(entry.Reason))
+			records = append(records, []string{s.Infos.FullName(), entry.Name, entry.Status, entry.Rom, entry.Reason, entry.CRC, entry.Patched})
+		}
+
+		w.Flush()
+		fmt.Printf("\n")
+	}
+
+	if h.Options.ReportCSV == "" {
+		return nil
+	}
+
+	if !h.Options.Quiet {
+		fmt.Printf("Writing report: %s\n", h.Options.ReportCSV)
+	}
+
+	return writeCSV(h.Options.ReportCSV, reportHeader, records)
+}
+
+// formatCounts returns given counters as a single line, eg: "Strict: 4, Ignore beta: 1"
+func formatCounts(counts []system.Count) string {
+	result := []string{}
+	for _, c := range counts {
+		result = append(result, fmt.Sprintf("%s: %d", c.Name, c.Count))
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// writeCSV writes given records to given CSV file path, after given header
+func writeCSV(filePath string, header []string, records [][]string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(records)
+
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// systemsByName sorts systems by full name
+type systemsByName []*system.System
+
+// Implements sort.Interface
+func (a systemsByName) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a systemsByName) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a systemsByName) Less(i, j int) bool {
+	return a[i].Infos.FullName() < a[j].Infos.FullName()
+}
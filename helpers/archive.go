@@ -0,0 +1,213 @@
+package helpers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRomSize is the maximum size of rom data read in memory, eg: to check, patch or convert a rom, bigger roms are not read
+const MaxRomSize = 64 * 1024 * 1024
+
+// ArchiveEntry represents a file in an archive
+type ArchiveEntry struct {
+	Path string
+	Size int64
+	CRC  string
+}
+
+// ListArchive returns all files in given archive, thanks to the 7z tool. An already extracted archive directory is listed too.
+func ListArchive(filePath string) ([]ArchiveEntry, error) {
+	result := []ArchiveEntry{}
+
+	if IsDir(filePath) {
+		return listDir(filePath)
+	}
+
+	output, err := ExecCmdOutput("7z", []string{"l", "-slt", filePath})
+	if err != nil {
+		return result, err
+	}
+
+	// skip archive infos
+	i := strings.Index(output, "----------")
+	if i < 0 {
+		return result, nil
+	}
+
+	// entries are separated by empty lines
+	for _, block := range strings.Split(output[i:], "\n\n") {
+		entry := ArchiveEntry{}
+		folder := false
+
+		for _, line := range strings.Split(block, "\n") {
+			parts := strings.SplitN(strings.TrimSpace(line), " = ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			switch parts[0] {
+			case "Path":
+				entry.Path = parts[1]
+			case "Folder":
+				folder = (parts[1] == "+")
+			case "Size":
+				entry.Size, _ = strconv.ParseInt(parts[1], 10, 64)
+			case "CRC":
+				entry.CRC = parts[1]
+			}
+		}
+
+		if (entry.Path != "") && !folder {
+			result = append(result, entry)
+		}
+	}
+
+	return result, nil
+}
+
+// listDir returns all files in given directory and its subdirectories, with paths relative to that directory
+func listDir(dir string) ([]ArchiveEntry, error) {
+	result := []ArchiveEntry{}
+
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, ArchiveEntry{Path: relPath, Size: info.Size()})
+
+		return nil
+	})
+
+	return result, err
+}
+
+// CheckZip reads all files in given zip archive, and returns an error if the archive or a file checksum is invalid
+func CheckZip(filePath string) error {
+	z, err := zip.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(ioutil.Discard, rc)
+		rc.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteZip writes a zip archive at given path, containing a single file with given name and data
+func WriteZip(filePath string, name string, data []byte) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	z := zip.NewWriter(f)
+
+	w, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err == nil {
+		_, err = w.Write(data)
+	}
+
+	if err == nil {
+		err = z.Close()
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadRom returns at most given size of first bytes of given rom file, and the rom file name. For a zipped rom, the first file in zip archive is read.
+func ReadRom(filePath string, size int64) ([]byte, string, error) {
+	if strings.ToLower(filepath.Ext(filePath)) != ".zip" {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+
+		data, err := ioutil.ReadAll(io.LimitReader(f, size))
+		return data, path.Base(filePath), err
+	}
+
+	z, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer z.Close()
+
+	f := firstZipFile(z)
+	if f == nil {
+		return nil, "", fmt.Errorf("Empty zip archive: %s", filePath)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(rc, size))
+
+	return data, path.Base(f.Name), err
+}
+
+// ZipCRC returns the CRC32 of the first file in given zip archive, as stored in archive
+func ZipCRC(filePath string) (uint32, error) {
+	z, err := zip.OpenReader(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer z.Close()
+
+	f := firstZipFile(z)
+	if f == nil {
+		return 0, fmt.Errorf("Empty zip archive: %s", filePath)
+	}
+
+	return f.CRC32, nil
+}
+
+// firstZipFile returns the first file in given zip archive, or nil if that archive has no file
+func firstZipFile(z *zip.ReadCloser) *zip.File {
+	for _, f := range z.File {
+		if !f.FileInfo().IsDir() {
+			return f
+		}
+	}
+
+	return nil
+}
@@ -42,3 +42,18 @@ func MustExecCmd(name string, args []string) {
 		log.Fatalln("Fatal cmd error")
 	}
 }
+
+// ExecCmdOutput executes a command, and returns its standard output
+func ExecCmdOutput(name string, args []string) (string, error) {
+	cmd := exec.Command(name, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		log.Printf("Cmd error: %v\n%s", err, stderr.String())
+	}
+
+	return string(output), err
+}
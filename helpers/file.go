@@ -1,6 +1,11 @@
 package helpers
 
-import "path"
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+)
 
 func FileBase(filePath string) string {
 	fileName := path.Base(filePath)
@@ -8,3 +13,35 @@ func FileBase(filePath string) string {
 
 	return fileName[:len(fileName)-len(fileExt)]
 }
+
+// IsDir returns true if given path is an existing directory
+func IsDir(filePath string) bool {
+	info, err := os.Stat(filePath)
+	return (err == nil) && info.IsDir()
+}
+
+// IsInDir returns true if given path is inside given directory
+func IsInDir(filePath string, dir string) bool {
+	return strings.HasPrefix(path.Clean(filePath), path.Clean(dir)+"/")
+}
+
+// CopyFile copies given file to given destination path
+func CopyFile(filePath string, destPath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
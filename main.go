@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
 	"github.com/aymerick/charette/core"
-	"github.com/aymerick/charette/harvester"
 )
 
 const (
@@ -24,11 +25,50 @@ var (
 	fOutput string
 	fTmpDir string
 
+	fMergeSets bool
+	fSystem    string
+
+	fDats       string
+	fSkippers   string
+	fQuarantine string
+
 	fRegions string
 	fStrict  bool
 	fInsane  bool
 	fUnzip   bool
 
+	fPreferSystems string
+	fOverrides     string
+
+	fIncludeList string
+	fExcludeList string
+
+	fMaxSize       string
+	fSystemMaxSize string
+	fSystemWeights string
+
+	fRegionScoring string
+	fWorldAny      bool
+
+	fVideo      string
+	fVideoFirst bool
+
+	fM3u      bool
+	fGamelist bool
+
+	fMediaDir  string
+	fMediaLink bool
+
+	fCheckHeaders bool
+	fN64Format    string
+	fHeaders      string
+
+	fPatches   string
+	fPatchMode string
+
+	fExportDats string
+	fReportCSV  string
+
 	fKeepProto  bool
 	fKeepBeta   bool
 	fKeepSample bool
@@ -41,44 +81,178 @@ var (
 	fVersion bool
 )
 
-func init() {
-	// get current directory
-	curDir, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
+// addInputFlags adds flags to select input archives
+func addInputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fInput, "input", curDir(), "Path to no-intro archives directory, or path to a single no-intro archive file or extracted directory")
+	fs.StringVar(&fSystem, "system", "", "System of single archive input, eg: 'Nintendo - Game Boy' or 'gb', when it can't be found from archive name")
+	fs.BoolVar(&fMergeSets, "merge-sets", false, "Process all dated copies of a system set, instead of the newest one only")
+	fs.StringVar(&fTmpDir, "tmp", path.Join(curDir(), defaultTmpDir), "Path to temporary working directory")
+}
 
-	// flags
-	flag.StringVar(&fInput, "input", curDir, "Path to no-intro archives directory, or path to a single no-intro archive file")
-	flag.StringVar(&fOutput, "output", path.Join(curDir, defaultOutput), "Path to output directory")
-	flag.StringVar(&fTmpDir, "tmp", path.Join(curDir, defaultTmpDir), "Path to temporary working directory")
+// addOutputFlags adds the output directory flag
+func addOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fOutput, "output", path.Join(curDir(), defaultOutput), "Path to output directory")
+}
+
+// addDatFlags adds the DAT files flag
+func addDatFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fDats, "dat", "", "Paths to no-intro DAT files, or to directories containing DAT files, separated by commas")
+	fs.StringVar(&fSkippers, "header-skippers", "", "Paths to ClrMamePro header skipper definitions, or to directories containing them, separated by commas")
+}
+
+// addSelectionFlags adds flags that change roms selection
+func addSelectionFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fRegions, "regions", defaultRegions, "Preferred regions")
+	fs.BoolVar(&fStrict, "strict", false, "Skip games that are not in preferred regions")
+	fs.BoolVar(&fInsane, "insane", false, "Activates flags: -keep-proto -keep-beta -keep-sample -keep-demo -keep-pirate -keep-promo")
+
+	fs.StringVar(&fRegionScoring, "region-scoring", core.RegionScoreBest, "Region scoring mode: 'best' only counts the best preferred region of a rom, 'coverage' favors roms matching more preferred regions")
+	fs.BoolVar(&fWorldAny, "world-any", false, "Treat 'World' roms as matching any preferred region")
+
+	fs.StringVar(&fVideo, "video", "", "Preferred video standard: 'pal' or 'ntsc'")
+	fs.BoolVar(&fVideoFirst, "video-first", false, "Rank video standard above preferred regions")
 
-	flag.StringVar(&fRegions, "regions", defaultRegions, "Preferred regions")
-	flag.BoolVar(&fStrict, "strict", false, "Skip games that are not in preferred regions")
-	flag.BoolVar(&fInsane, "insane", false, "Activates flags: -keep-proto -keep-beta -keep-sample -keep-demo -keep-pirate -keep-promo")
-	flag.BoolVar(&fUnzip, "unzip", false, "Unzip roms")
+	fs.StringVar(&fPreferSystems, "prefer-systems", "", "Preferred systems when a game is found in several systems sharing the same output directory, eg: 'Bandai - WonderSwan,Microsoft - MSX'")
 
-	flag.BoolVar(&fKeepProto, "keep-proto", false, "Keep roms tagged with 'Promo'")
-	flag.BoolVar(&fKeepBeta, "keep-beta", false, "Keep roms tagged with 'Beta'")
-	flag.BoolVar(&fKeepSample, "keep-sample", false, "Keep roms tagged with 'Sample'")
-	flag.BoolVar(&fKeepDemo, "keep-demo", false, "Keep roms tagged with 'Demo'")
-	flag.BoolVar(&fKeepPirate, "keep-pirate", false, "Keep roms tagged with 'Pirate'")
-	flag.BoolVar(&fKeepPromo, "keep-promo", false, "Keep roms tagged with 'Promo'")
+	fs.StringVar(&fOverrides, "overrides", "", "Path to a JSON file with per-game overrides to pin a rom, exclude a game or force a game")
 
-	flag.BoolVar(&fQuiet, "quiet", false, "Activate quiet output")
-	flag.BoolVar(&fDebug, "debug", false, "Activate debug output")
-	flag.BoolVar(&fVersion, "version", false, "Display charette version")
+	fs.StringVar(&fIncludeList, "include-list", "", "Path to a curated list of games to include, systems without entries in that list are not filtered")
+	fs.StringVar(&fExcludeList, "exclude-list", "", "Path to a curated list of games to exclude")
+
+	fs.BoolVar(&fKeepProto, "keep-proto", false, "Keep roms tagged with 'Promo'")
+	fs.BoolVar(&fKeepBeta, "keep-beta", false, "Keep roms tagged with 'Beta'")
+	fs.BoolVar(&fKeepSample, "keep-sample", false, "Keep roms tagged with 'Sample'")
+	fs.BoolVar(&fKeepDemo, "keep-demo", false, "Keep roms tagged with 'Demo'")
+	fs.BoolVar(&fKeepPirate, "keep-pirate", false, "Keep roms tagged with 'Pirate'")
+	fs.BoolVar(&fKeepPromo, "keep-promo", false, "Keep roms tagged with 'Promo'")
+}
+
+// addHarvestFlags adds flags that change what is written into output directory
+func addHarvestFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&fUnzip, "unzip", false, "Unzip roms")
+
+	fs.BoolVar(&fM3u, "m3u", false, "Write a m3u playlist for each multi-disc game")
+	fs.BoolVar(&fGamelist, "gamelist", false, "Write an EmulationStation gamelist.xml into each system directory")
+
+	fs.StringVar(&fMediaDir, "media-dir", "", "Path to local media mirror (box art, screenshots and descriptions)")
+	fs.BoolVar(&fMediaLink, "media-link", false, "Link media files instead of copying them")
+
+	fs.BoolVar(&fCheckHeaders, "check-headers", false, "Inspect internal headers of selected roms: wrong system, bad checksums and region guess for '(Unknown)' roms")
+}
+
+// addFormatFlags adds flags that set the expected format of output roms
+func addFormatFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fN64Format, "n64-format", "", "Convert N64 roms to given byte order: 'z64' (big endian), 'v64' (byte swapped) or 'n64' (little endian)")
+	fs.StringVar(&fHeaders, "headers", "", "Header actions per system: 'keep', 'strip', 'add' or 'normalize', eg: 'nes=add,snes=strip'")
+}
+
+// addPatchFlags adds patches flags
+func addPatchFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fPatches, "patches", "", "Path to patches directory: IPS, BPS and UPS patches named after the no-intro name or the CRC32 of the rom they apply to")
+	fs.StringVar(&fPatchMode, "patch-mode", core.PatchBeside, "Where patched roms are written: 'beside' the original rom or 'replace' it, or 'softpatch' to copy patches next to roms, renamed after them")
+}
+
+// addExportFlags adds selection export flags
+func addExportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fExportDats, "export-dats", "", "Path to directory where a Logiqx XML DAT of selected roms is written for each system, with hashes from DAT files")
+}
+
+// addReportFlags adds completeness report flags
+func addReportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fReportCSV, "csv", "", "Path to CSV file where the report is written")
+}
+
+// addDiffFlags adds flags to process compared archives
+func addDiffFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fSystem, "system", "", "System of compared archives, eg: 'Nintendo - Game Boy' or 'gb', when it can't be found from archive name")
+	fs.StringVar(&fTmpDir, "tmp", path.Join(curDir(), defaultTmpDir), "Path to temporary working directory")
+}
+
+// addBudgetFlags adds storage budget flags
+func addBudgetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fMaxSize, "max-size", "", "Storage budget for all systems, eg: '32G'")
+	fs.StringVar(&fSystemMaxSize, "system-max-size", "", "Storage budgets per system, eg: 'gb=2G,Nintendo - Game Boy Advance=8G'")
+	fs.StringVar(&fSystemWeights, "system-weights", "", "Storage budget priority weights per system, default weight is 1, eg: 'gba=2,gb=1'")
+}
+
+// addQuarantineFlags adds the quarantine directory flag
+func addQuarantineFlags(fs *flag.FlagSet) {
+	fs.StringVar(&fQuarantine, "quarantine", "", "Path to directory where unwanted files are moved, they are only reported if not set")
+}
+
+// addCommonFlags adds flags shared by all commands
+func addCommonFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&fQuiet, "quiet", false, "Activate quiet output")
+	fs.BoolVar(&fDebug, "debug", false, "Activate debug output")
 }
 
 func main() {
-	flag.Parse()
+	args := os.Args[1:]
+
+	// default command
+	name := "harvest"
+
+	if (len(args) > 0) && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+
+	switch name {
+	case "help":
+		if len(args) > 0 {
+			if cmd := findCommand(args[0]); cmd != nil {
+				cmd.flagSet().Usage()
+				os.Exit(0)
+			}
+		}
+
+		usage()
+		os.Exit(0)
+
+	case "version":
+		fmt.Println(version)
+		os.Exit(0)
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	fs := cmd.flagSet()
+	if name == "harvest" {
+		fs.BoolVar(&fVersion, "version", false, "Display charette version")
+	}
+
+	fs.Parse(args)
 
-	// check flags
 	if fVersion {
 		fmt.Println(version)
 		os.Exit(0)
 	}
 
+	if err := cmd.run(options(), fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage displays the list of commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: charette [command] [flags] [arguments]\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "    %-10s %s\n", cmd.name, cmd.short)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nDefault command is 'harvest'. Use 'charette help [command]' for more information about a command.\n")
+}
+
+// options computes options from flags
+func options() *core.Options {
 	if fInsane {
 		fKeepProto = true
 		fKeepBeta = true
@@ -106,48 +280,182 @@ func main() {
 
 	fTmpDir = path.Clean(fTmpDir)
 
+	if fQuarantine != "" {
+		fQuarantine = path.Clean(fQuarantine)
+	}
+
 	if fDebug {
 		fQuiet = false
 	}
 
-	if !fQuiet {
-		fmt.Printf("charette v%s\n", version)
-		fmt.Printf("   input: %s\n", fInput)
-		fmt.Printf("   output: %s\n", fOutput)
-		fmt.Printf("   tmp dir: %s\n", fTmpDir)
+	result := core.NewOptions()
+
+	result.Input = fInput
+	result.Output = fOutput
+	result.Tmp = fTmpDir
+	result.MergeSets = fMergeSets
+	result.System = fSystem
+
+	if fDats != "" {
+		for _, p := range strings.Split(fDats, ",") {
+			result.Dats = append(result.Dats, path.Clean(strings.TrimSpace(p)))
+		}
 	}
 
-	if (fInput == fOutput) || (fInput == fTmpDir) {
-		panic("Output and tmp directories can't be the same as input directory")
+	if fSkippers != "" {
+		for _, p := range strings.Split(fSkippers, ",") {
+			result.HeaderSkippers = append(result.HeaderSkippers, path.Clean(strings.TrimSpace(p)))
+		}
 	}
 
-	// computes options
-	options := core.NewOptions()
+	result.Quarantine = fQuarantine
 
-	options.Input = fInput
-	options.Output = fOutput
-	options.Tmp = fTmpDir
+	if unknown := core.UnknownRegions(fRegions); len(unknown) > 0 {
+		fmt.Fprintf(os.Stderr, "Invalid regions: %s\n", strings.Join(unknown, ", "))
+		os.Exit(2)
+	}
 
-	options.Regions = core.ExtractRegions(fRegions)
+	result.Regions = core.ExtractRegions(fRegions)
+	if fDebug {
+		fmt.Printf("Preferred regions: %s\n", strings.Join(result.Regions, ", "))
+	}
 
-	options.Strict = fStrict
+	result.Strict = fStrict
 
-	options.KeepProto = fKeepProto
-	options.KeepBeta = fKeepBeta
-	options.KeepSample = fKeepSample
-	options.KeepDemo = fKeepDemo
-	options.KeepPirate = fKeepPirate
-	options.KeepPromo = fKeepPromo
+	if fRegionScoring != "" {
+		result.RegionScoring = core.ExtractRegionScoring(fRegionScoring)
+		if result.RegionScoring == "" {
+			fmt.Fprintf(os.Stderr, "Invalid region scoring mode: %s\n", fRegionScoring)
+			os.Exit(2)
+		}
+	}
 
-	options.Quiet = fQuiet
-	options.Debug = fDebug
-	options.Unzip = fUnzip
+	result.WorldAny = fWorldAny
 
-	// run harvester
-	h := harvester.New(options)
-	if err := h.Run(); err != nil {
-		panic(err)
+	result.Video = core.ExtractVideo(fVideo)
+	if (fVideo != "") && (result.Video == "") {
+		fmt.Fprintf(os.Stderr, "Invalid video standard: %s\n", fVideo)
+		os.Exit(2)
+	}
+
+	result.VideoFirst = fVideoFirst
+
+	if fPreferSystems != "" {
+		for _, name := range strings.Split(fPreferSystems, ",") {
+			result.PreferredSystems = append(result.PreferredSystems, strings.TrimSpace(name))
+		}
+	}
+
+	if fOverrides != "" {
+		result.Overrides = path.Clean(fOverrides)
+	}
+
+	if fIncludeList != "" {
+		result.IncludeList = path.Clean(fIncludeList)
 	}
+
+	if fExcludeList != "" {
+		result.ExcludeList = path.Clean(fExcludeList)
+	}
+
+	if err := budgetOptions(result); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	result.KeepProto = fKeepProto
+	result.KeepBeta = fKeepBeta
+	result.KeepSample = fKeepSample
+	result.KeepDemo = fKeepDemo
+	result.KeepPirate = fKeepPirate
+	result.KeepPromo = fKeepPromo
+
+	result.Quiet = fQuiet
+	result.Debug = fDebug
+	result.Unzip = fUnzip
+	result.Playlists = fM3u
+	result.Gamelist = fGamelist
+
+	if fMediaDir != "" {
+		result.MediaDir = path.Clean(fMediaDir)
+	}
+	result.MediaLink = fMediaLink
+	result.CheckHeaders = fCheckHeaders
+
+	result.N64Format = core.ExtractN64Format(fN64Format)
+	if (fN64Format != "") && (result.N64Format == "") {
+		fmt.Fprintf(os.Stderr, "Invalid N64 format: %s\n", fN64Format)
+		os.Exit(2)
+	}
+
+	if fPatches != "" {
+		result.Patches = path.Clean(fPatches)
+	}
+
+	if fPatchMode != "" {
+		if result.PatchMode = core.ExtractPatchMode(fPatchMode); result.PatchMode == "" {
+			fmt.Fprintf(os.Stderr, "Invalid patch mode: %s\n", fPatchMode)
+			os.Exit(2)
+		}
+	}
+
+	if fExportDats != "" {
+		result.ExportDats = path.Clean(fExportDats)
+	}
+
+	if fReportCSV != "" {
+		result.ReportCSV = path.Clean(fReportCSV)
+	}
+
+	headers, err := core.ParseSettings(fHeaders)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	for name, str := range headers {
+		if result.HeaderActions[name] = core.ExtractHeaderAction(str); result.HeaderActions[name] == "" {
+			fmt.Fprintf(os.Stderr, "Invalid header action: %s\n", str)
+			os.Exit(2)
+		}
+	}
+
+	return result
+}
+
+// budgetOptions sets storage budget options from flags
+func budgetOptions(result *core.Options) error {
+	var err error
+
+	if fMaxSize != "" {
+		if result.MaxSize, err = core.ParseSize(fMaxSize); err != nil {
+			return err
+		}
+	}
+
+	sizes, err := core.ParseSettings(fSystemMaxSize)
+	if err != nil {
+		return err
+	}
+
+	for name, str := range sizes {
+		if result.SystemMaxSizes[name], err = core.ParseSize(str); err != nil {
+			return err
+		}
+	}
+
+	weights, err := core.ParseSettings(fSystemWeights)
+	if err != nil {
+		return err
+	}
+
+	for name, str := range weights {
+		if result.SystemWeights[name], err = strconv.Atoi(str); err != nil {
+			return fmt.Errorf("Invalid system weight: %s", str)
+		}
+	}
+
+	return nil
 }
 
 // curDir returns current directory
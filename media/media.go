@@ -0,0 +1,80 @@
+package media
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// Boxart is the box art media kind
+	Boxart = "boxart"
+
+	// Screenshot is the screenshot media kind
+	Screenshot = "screenshots"
+
+	// Description is the description media kind
+	Description = "descriptions"
+)
+
+// Kinds holds all supported media kinds
+var Kinds = []string{Boxart, Screenshot, Description}
+
+// extensions holds the supported file extensions for each media kind
+var extensions = map[string][]string{
+	Boxart:      {".png", ".jpg", ".jpeg"},
+	Screenshot:  {".png", ".jpg", ".jpeg"},
+	Description: {".txt"},
+}
+
+// Library represents a local mirror of media files, with that layout:
+//
+//	<dir>/<system>/<kind>/<key>.<ext>
+//
+// where <system> is either the "<Manufacturer> - <Name>" system name or the system roms directory,
+// <kind> is one of "boxart", "screenshots" and "descriptions", and <key> is either the rom CRC32 or the no-intro rom name
+type Library struct {
+	Dir string
+}
+
+// New instanciates a new Library
+func New(dir string) *Library {
+	return &Library{
+		Dir: dir,
+	}
+}
+
+// Find returns the media files found for given rom, indexed by kind. Matching is done on CRC first, then on no-intro name.
+func (l *Library) Find(systemDirs []string, crc string, name string) map[string]string {
+	result := map[string]string{}
+
+	keys := []string{}
+	if crc != "" {
+		keys = append(keys, strings.ToUpper(crc), strings.ToLower(crc))
+	}
+	keys = append(keys, name)
+
+	for _, kind := range Kinds {
+		if filePath := l.find(systemDirs, kind, keys); filePath != "" {
+			result[kind] = filePath
+		}
+	}
+
+	return result
+}
+
+// find returns the first existing file for given media kind and keys
+func (l *Library) find(systemDirs []string, kind string, keys []string) string {
+	for _, key := range keys {
+		for _, systemDir := range systemDirs {
+			for _, ext := range extensions[kind] {
+				filePath := path.Join(l.Dir, systemDir, kind, key+ext)
+				if _, err := os.Stat(filePath); err == nil {
+					return filePath
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,73 @@
+package media
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLibraryFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"Nintendo - Game Boy/boxart/46DF91AD.png",
+		"Nintendo - Game Boy/boxart/Tetris (World).png",
+		"Nintendo - Game Boy/screenshots/Tetris (World).jpg",
+		"gb/descriptions/46df91ad.txt",
+		"gb/boxart/Kwirk (USA).jpeg",
+	}
+
+	for _, file := range files {
+		os.MkdirAll(path.Dir(path.Join(dir, file)), 0777)
+
+		if err := ioutil.WriteFile(path.Join(dir, file), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	systemDirs := []string{"Nintendo - Game Boy", "gb"}
+
+	tests := []struct {
+		crc      string
+		name     string
+		expected map[string]string
+	}{
+		// CRC first, then name, in any system directory
+		{"46df91ad", "Tetris (World)", map[string]string{
+			Boxart:      "Nintendo - Game Boy/boxart/46DF91AD.png",
+			Screenshot:  "Nintendo - Game Boy/screenshots/Tetris (World).jpg",
+			Description: "gb/descriptions/46df91ad.txt",
+		}},
+		// unknown CRC
+		{"", "Tetris (World)", map[string]string{
+			Boxart:     "Nintendo - Game Boy/boxart/Tetris (World).png",
+			Screenshot: "Nintendo - Game Boy/screenshots/Tetris (World).jpg",
+		}},
+		{"12345678", "Kwirk (USA)", map[string]string{
+			Boxart: "gb/boxart/Kwirk (USA).jpeg",
+		}},
+		{"12345678", "Alleyway (World)", map[string]string{}},
+	}
+
+	l := New(dir)
+
+	for _, test := range tests {
+		result := l.Find(systemDirs, test.crc, test.name)
+
+		if len(result) != len(test.expected) {
+			t.Errorf("Failed to find media of '%s', got %v but expected %v", test.name, result, test.expected)
+			continue
+		}
+
+		for kind, file := range test.expected {
+			if result[kind] != path.Join(dir, file) {
+				t.Errorf("Failed to find %s of '%s', got '%s' but expected '%s'", kind, test.name, result[kind], file)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymerick/charette/rom"
+)
+
+// List represents an overrides file
+type List struct {
+	Overrides []*Override
+
+	// file path
+	File string
+}
+
+// Override represents a manual selection rule for a game
+type Override struct {
+	// system full name, name or output directory, eg: "Nintendo - Game Boy", "Game Boy" or "gb"
+	System string `json:"system"`
+
+	// game name, matched with a case insensitive and punctuation agnostic lookup
+	Game string `json:"game"`
+
+	// rom filename to select, with or without extension
+	Pin string `json:"pin,omitempty"`
+
+	// skip game entirely
+	Exclude bool `json:"exclude,omitempty"`
+
+	// select game even if its roms would be skipped
+	Force bool `json:"force,omitempty"`
+
+	// applied actions
+	Applied []string `json:"-"`
+}
+
+// New instanciates a new List
+func New() *List {
+	return &List{}
+}
+
+// Load reads overrides file at given path
+func Load(filePath string) (*List, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	result.File = filePath
+
+	return result, nil
+}
+
+// Parse parses given overrides content, a JSON array of overrides
+func Parse(data []byte) (*List, error) {
+	result := New()
+
+	if err := json.Unmarshal(data, &result.Overrides); err != nil {
+		return nil, err
+	}
+
+	for i, o := range result.Overrides {
+		if (o.System == "") || (o.Game == "") {
+			return nil, fmt.Errorf("override #%d: system and game are mandatory", i+1)
+		}
+
+		if o.Exclude && (o.Force || (o.Pin != "")) {
+			return nil, fmt.Errorf("override #%d: a game can't be both excluded and selected: %s", i+1, o.Game)
+		}
+
+		if !o.Exclude && !o.Force && (o.Pin == "") {
+			return nil, fmt.Errorf("override #%d: no action for game: %s", i+1, o.Game)
+		}
+	}
+
+	return result, nil
+}
+
+// Find returns the override for given game in a system with one of given names, or nil if not found
+func (l *List) Find(systemNames []string, game string) *Override {
+	if l == nil {
+		return nil
+	}
+
+	key := rom.NormalizeTitle(game)
+
+	for _, o := range l.Overrides {
+		if (rom.NormalizeTitle(o.Game) == key) && o.matchSystem(systemNames) {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// Applied returns overrides that were applied
+func (l *List) Applied() []*Override {
+	result := []*Override{}
+
+	if l != nil {
+		for _, o := range l.Overrides {
+			if len(o.Applied) > 0 {
+				result = append(result, o)
+			}
+		}
+	}
+
+	return result
+}
+
+// Unused returns overrides that were never applied
+func (l *List) Unused() []*Override {
+	result := []*Override{}
+
+	if l != nil {
+		for _, o := range l.Overrides {
+			if len(o.Applied) == 0 {
+				result = append(result, o)
+			}
+		}
+	}
+
+	return result
+}
+
+// String implements fmt.Stringer
+func (o *Override) String() string {
+	return fmt.Sprintf("%s / %s", o.System, o.Game)
+}
+
+// Pinned returns true if given rom filename is the pinned one
+func (o *Override) Pinned(fileName string) bool {
+	if (o == nil) || (o.Pin == "") {
+		return false
+	}
+
+	return (fileName == o.Pin) || (strings.TrimSuffix(fileName, filepath.Ext(fileName)) == o.Pin)
+}
+
+// Apply records given applied action, once
+func (o *Override) Apply(action string) {
+	for _, a := range o.Applied {
+		if a == action {
+			return
+		}
+	}
+
+	o.Applied = append(o.Applied, action)
+}
+
+// matchSystem returns true if override system is one of given names
+func (o *Override) matchSystem(names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(o.System, name) {
+			return true
+		}
+	}
+
+	return false
+}
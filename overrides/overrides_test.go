@@ -0,0 +1,90 @@
+package overrides
+
+import "testing"
+
+const sample = `[
+	{"system": "Nintendo - Game Boy", "game": "Tetris", "pin": "Tetris (Japan) (En)"},
+	{"system": "gb", "game": "Alleyway", "exclude": true},
+	{"system": "Game Boy Color", "game": "Pokemon - Crystal Version", "force": true}
+]`
+
+func TestParse(t *testing.T) {
+	l, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	if len(l.Overrides) != 3 {
+		t.Fatalf("Overrides parsing failed, got %d overrides but expected 3", len(l.Overrides))
+	}
+
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{`[{"system": "gb", "game": "Tetris", "force": true}]`, true},
+		{`[{"system": "gb", "game": "Tetris"}]`, false},
+		{`[{"game": "Tetris", "force": true}]`, false},
+		{`[{"system": "gb", "game": "Tetris", "exclude": true, "pin": "Tetris (World)"}]`, false},
+		{`{"system": "gb"}`, false},
+	}
+
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.data)); (err == nil) != test.valid {
+			t.Errorf("Overrides validation failed, got error '%v' for: %s", err, test.data)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	l, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal("Parse failed", err)
+	}
+
+	gb := []string{"Nintendo - Game Boy", "Game Boy", "gb"}
+	gbc := []string{"Nintendo - Game Boy Color", "Game Boy Color", "gbc"}
+
+	tests := []struct {
+		systems  []string
+		game     string
+		expected *Override
+	}{
+		{gb, "Tetris", l.Overrides[0]},
+		{gb, "tetris", l.Overrides[0]},
+		{gbc, "Tetris", nil},
+		{gb, "Alleyway", l.Overrides[1]},
+		{gbc, "Pokemon: Crystal Version", l.Overrides[2]},
+		{gb, "Pokemon - Crystal Version", nil},
+	}
+
+	for _, test := range tests {
+		if o := l.Find(test.systems, test.game); o != test.expected {
+			t.Errorf("Override lookup failed for '%s' in %v, got '%v' but expected '%v'", test.game, test.systems, o, test.expected)
+		}
+	}
+
+	var empty *List
+	if o := empty.Find(gb, "Tetris"); o != nil {
+		t.Errorf("Override lookup in nil list failed, got '%v'", o)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	o := &Override{Pin: "Tetris (Japan) (En)"}
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"Tetris (Japan) (En).zip", true},
+		{"Tetris (Japan) (En)", true},
+		{"Tetris (World) (Rev A).zip", false},
+	}
+
+	for _, test := range tests {
+		if result := o.Pinned(test.fileName); result != test.expected {
+			t.Errorf("Pinned check failed for '%s', got %v but expected %v", test.fileName, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rCRC matches a patch file name keyed by the CRC32 of the rom it applies to, eg: "1A2B3C4D [T-En].ips"
+var rCRC = regexp.MustCompile(`^([0-9A-Fa-f]{8})(?:$|[ _\-\[\(])`)
+
+// Library represents a directory of patches, named after the no-intro name or the CRC32 of the rom they apply to, eg: "Final Fantasy V (Japan) [T-En].bps"
+type Library struct {
+	// directory path
+	Dir string
+
+	// patch files paths
+	files []string
+}
+
+// NewLibrary scans given patches directory and its subdirectories
+func NewLibrary(dir string) (*Library, error) {
+	result := &Library{Dir: dir}
+
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && IsPatchFile(info.Name()) {
+			result.files = append(result.files, filePath)
+		}
+
+		return nil
+	})
+
+	sort.Strings(result.files)
+
+	return result, err
+}
+
+// Find returns the patch files for given rom name without extension, eg: "Final Fantasy V (Japan)", or for given rom CRC32. Patches keyed by name win over patches keyed by CRC32: the crc function is only called if no patch is keyed by given name, and if some patches are keyed by CRC32.
+func (l *Library) Find(name string, crc func() string) []string {
+	result := []string{}
+	if l == nil {
+		return result
+	}
+
+	keyed := []string{}
+
+	for _, file := range l.files {
+		if matchName(Base(file), name) {
+			result = append(result, file)
+		} else if ExpectedCRC(file) != "" {
+			keyed = append(keyed, file)
+		}
+	}
+
+	if (len(result) > 0) || (len(keyed) == 0) || (crc == nil) {
+		return result
+	}
+
+	romCRC := crc()
+	if romCRC == "" {
+		return result
+	}
+
+	for _, file := range keyed {
+		if strings.EqualFold(ExpectedCRC(file), romCRC) {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// matchName returns true if given patch file name without extension is keyed by given rom name. The rom name can be followed by a label, but not by another no-intro tag, eg: "Final Fantasy V (Japan) [T-En]" is not keyed by "Final Fantasy V".
+func matchName(base string, name string) bool {
+	if base == name {
+		return true
+	}
+
+	return strings.HasPrefix(base, name+" ") && !strings.HasPrefix(base[len(name):], " (")
+}
+
+// Base returns patch file name without extension
+func Base(filePath string) string {
+	name := filepath.Base(filePath)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// ExpectedCRC returns the CRC32 of the rom that given patch file is keyed by, or an empty string if that patch is keyed by a rom name
+func ExpectedCRC(filePath string) string {
+	match := rCRC.FindStringSubmatch(Base(filePath))
+	if match == nil {
+		return ""
+	}
+
+	return strings.ToUpper(match[1])
+}
+
+// Label returns the part of patch file name that follows the rom name or CRC32, eg: " [T-En]" for "Final Fantasy V (Japan) [T-En].bps", or an empty string if none
+func Label(filePath string, name string) string {
+	base := Base(filePath)
+
+	if strings.HasPrefix(base, name) {
+		return base[len(name):]
+	}
+
+	if crc := ExpectedCRC(filePath); crc != "" {
+		return base[len(crc):]
+	}
+
+	return ""
+}
@@ -0,0 +1,94 @@
+package patch
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLibraryFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-patches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"Final Fantasy V (Japan) [T-En].bps",
+		"snes/Seiken Densetsu 3 (Japan).ips",
+		"1A2B3C4D [T-Fr].ups",
+		"Final Fantasy V (Japan) (Rev 1) [T-En].bps",
+		"Final Fantasy V (Japan).txt",
+		"5E6F7A8B [T-De].ips",
+	}
+
+	for _, file := range files {
+		filePath := path.Join(dir, file)
+
+		if err := os.MkdirAll(path.Dir(filePath), 0777); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filePath, []byte("PATCHEOF"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		crc      string
+		expected []string
+	}{
+		{"Final Fantasy V (Japan)", "", []string{"Final Fantasy V (Japan) [T-En].bps"}},
+		{"Seiken Densetsu 3 (Japan)", "", []string{"snes/Seiken Densetsu 3 (Japan).ips"}},
+		{"Chrono Trigger (Japan)", "1a2b3c4d", []string{"1A2B3C4D [T-Fr].ups"}},
+		{"Chrono Trigger (Japan)", "00000000", []string{}},
+		{"Seiken Densetsu 3 (Japan)", "5e6f7a8b", []string{"snes/Seiken Densetsu 3 (Japan).ips"}},
+	}
+
+	for _, test := range tests {
+		crc := test.crc
+		result := []string{}
+		computed := false
+
+		for _, file := range l.Find(test.name, func() string { computed = true; return crc }) {
+			rel, _ := filepath.Rel(dir, file)
+			result = append(result, rel)
+		}
+
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Patch lookup failed for '%s', got %v but expected %v", test.name, result, test.expected)
+		}
+
+		// CRC32 is only needed when no patch is keyed by name
+		if expected := (test.crc != "") && (test.name == "Chrono Trigger (Japan)"); computed != expected {
+			t.Errorf("Patch lookup failed for '%s', CRC computed: %v but expected %v", test.name, computed, expected)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		file     string
+		name     string
+		expected string
+	}{
+		{"Final Fantasy V (Japan) [T-En].bps", "Final Fantasy V (Japan)", " [T-En]"},
+		{"Final Fantasy V (Japan).bps", "Final Fantasy V (Japan)", ""},
+		{"1A2B3C4D [T-Fr].ups", "Chrono Trigger (Japan)", " [T-Fr]"},
+	}
+
+	for _, test := range tests {
+		if result := Label(test.file, test.name); result != test.expected {
+			t.Errorf("Label failed for '%s', got '%s' but expected '%s'", test.file, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// patch formats, which are also the patch file extensions
+const (
+	FormatIPS = "ips"
+	FormatBPS = "bps"
+	FormatUPS = "ups"
+)
+
+// Patch represents an IPS, BPS or UPS patch file
+type Patch struct {
+	// file path
+	File string
+
+	// patch format
+	Format string
+
+	// patch data
+	data []byte
+
+	// CRC32 of source data from file name, for IPS patches keyed by CRC32
+	crc string
+}
+
+// IsPatchFile returns true if given file name has a supported patch extension
+func IsPatchFile(fileName string) bool {
+	switch formatFromExt(fileName) {
+	case FormatIPS, FormatBPS, FormatUPS:
+		return true
+	}
+
+	return false
+}
+
+// Load reads patch file at given path
+func Load(filePath string) (*Patch, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Parse(data, formatFromExt(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	result.File = filePath
+	result.crc = ExpectedCRC(filePath)
+
+	return result, nil
+}
+
+// Parse checks given patch data, with given format
+func Parse(data []byte, format string) (*Patch, error) {
+	result := &Patch{Format: format, data: data}
+
+	switch format {
+	case FormatIPS:
+		if !bytes.HasPrefix(data, []byte("PATCH")) {
+			return nil, fmt.Errorf("Invalid IPS patch")
+		}
+
+	case FormatBPS, FormatUPS:
+		if !bytes.HasPrefix(data, []byte(strings.ToUpper(format)+"1")) || (len(data) < 16) {
+			return nil, fmt.Errorf("Invalid %s patch", strings.ToUpper(format))
+		}
+
+		if crc32.ChecksumIEEE(data[:len(data)-4]) != result.footer(2) {
+			return nil, fmt.Errorf("Bad %s patch checksum", strings.ToUpper(format))
+		}
+
+	default:
+		return nil, fmt.Errorf("Unsupported patch format: %s", format)
+	}
+
+	return result, nil
+}
+
+// SourceCRC returns the CRC32 of the source data that patch expects. IPS patches do not embed checksums, so the CRC32 is taken from the file name if that patch is keyed by CRC32, otherwise the second value is false.
+func (p *Patch) SourceCRC() (uint32, bool) {
+	if p.Format == FormatIPS {
+		crc, err := strconv.ParseUint(p.crc, 16, 32)
+		return uint32(crc), (p.crc != "") && (err == nil)
+	}
+
+	return p.footer(0), true
+}
+
+// Apply returns given source data patched. Source checksum is verified when known, and target checksum is verified for BPS and UPS patches.
+func (p *Patch) Apply(source []byte) ([]byte, error) {
+	if crc, ok := p.SourceCRC(); ok && (crc32.ChecksumIEEE(source) != crc) {
+		return nil, fmt.Errorf("Source checksum mismatch: %08X (expected %08X)", crc32.ChecksumIEEE(source), crc)
+	}
+
+	var result []byte
+	var err error
+
+	switch p.Format {
+	case FormatIPS:
+		result, err = applyIPS(p.data, source)
+	case FormatBPS:
+		result, err = applyBPS(p.data, source)
+	case FormatUPS:
+		result, err = applyUPS(p.data, source)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Format != FormatIPS {
+		if crc := p.footer(1); crc32.ChecksumIEEE(result) != crc {
+			return nil, fmt.Errorf("Target checksum mismatch: %08X (expected %08X)", crc32.ChecksumIEEE(result), crc)
+		}
+	}
+
+	return result, nil
+}
+
+// footer returns given checksum of BPS and UPS footer: 0 for source, 1 for target and 2 for patch
+func (p *Patch) footer(i int) uint32 {
+	offset := len(p.data) - 12 + i*4
+	return binary.LittleEndian.Uint32(p.data[offset : offset+4])
+}
+
+// formatFromExt returns the patch format corresponding to given file extension
+func formatFromExt(fileName string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+}
+
+// applyIPS applies given IPS patch data
+func applyIPS(data []byte, source []byte) ([]byte, error) {
+	result := make([]byte, len(source))
+	copy(result, source)
+
+	pos := 5
+
+	for {
+		if pos+3 > len(data) {
+			return nil, fmt.Errorf("Truncated IPS patch")
+		}
+
+		if string(data[pos:pos+3]) == "EOF" {
+			pos += 3
+			break
+		}
+
+		if pos+5 > len(data) {
+			return nil, fmt.Errorf("Truncated IPS patch")
+		}
+
+		offset := int(data[pos])<<16 | int(data[pos+1])<<8 | int(data[pos+2])
+		size := int(data[pos+3])<<8 | int(data[pos+4])
+		pos += 5
+
+		var chunk []byte
+
+		if size == 0 {
+			// RLE record
+			if pos+3 > len(data) {
+				return nil, fmt.Errorf("Truncated IPS patch")
+			}
+
+			size = int(data[pos])<<8 | int(data[pos+1])
+			chunk = bytes.Repeat([]byte{data[pos+2]}, size)
+			pos += 3
+		} else {
+			if pos+size > len(data) {
+				return nil, fmt.Errorf("Truncated IPS patch")
+			}
+
+			chunk = data[pos : pos+size]
+			pos += size
+		}
+
+		if offset+size > len(result) {
+			result = append(result, make([]byte, offset+size-len(result))...)
+		}
+
+		copy(result[offset:], chunk)
+	}
+
+	// truncation extension
+	if pos+3 <= len(data) {
+		if size := int(data[pos])<<16 | int(data[pos+1])<<8 | int(data[pos+2]); size < len(result) {
+			result = result[:size]
+		}
+	}
+
+	return result, nil
+}
+
+// reader reads variable length integers of BPS and UPS patches
+type reader struct {
+	data []byte
+	pos  int
+	end  int
+	err  error
+}
+
+// byte returns next patch byte
+func (r *reader) byte() byte {
+	if r.pos >= r.end {
+		r.err = fmt.Errorf("Truncated patch")
+		return 0
+	}
+
+	r.pos++
+
+	return r.data[r.pos-1]
+}
+
+// number returns next variable length integer
+func (r *reader) number() int {
+	result, shift := 0, 1
+
+	for r.err == nil {
+		x := r.byte()
+		result += int(x&0x7F) * shift
+
+		if x&0x80 != 0 {
+			break
+		}
+
+		shift <<= 7
+		result += shift
+	}
+
+	return result
+}
+
+// applyUPS applies given UPS patch data
+func applyUPS(data []byte, source []byte) ([]byte, error) {
+	r := &reader{data: data, pos: 4, end: len(data) - 12}
+
+	r.number()
+	result := make([]byte, r.number())
+	copy(result, source)
+
+	pos := 0
+
+	for (r.err == nil) && (r.pos < r.end) {
+		pos += r.number()
+
+		for r.err == nil {
+			x := r.byte()
+			if pos < len(result) {
+				result[pos] ^= x
+			}
+			pos++
+
+			if x == 0 {
+				break
+			}
+		}
+	}
+
+	return result, r.err
+}
+
+// applyBPS applies given BPS patch data
+func applyBPS(data []byte, source []byte) ([]byte, error) {
+	r := &reader{data: data, pos: 4, end: len(data) - 12}
+
+	r.number()
+	result := make([]byte, r.number())
+
+	// skip metadata
+	r.pos += r.number()
+
+	out, sourceRel, targetRel := 0, 0, 0
+
+	for (r.err == nil) && (r.pos < r.end) {
+		n := r.number()
+		command, length := n&3, (n>>2)+1
+
+		if out+length > len(result) {
+			return nil, fmt.Errorf("Invalid BPS patch, target overflow")
+		}
+
+		switch command {
+		case 0:
+			// source read
+			if out+length > len(source) {
+				return nil, fmt.Errorf("Invalid BPS patch, source overflow")
+			}
+
+			copy(result[out:], source[out:out+length])
+			out += length
+
+		case 1:
+			// target read
+			for i := 0; i < length; i++ {
+				result[out] = r.byte()
+				out++
+			}
+
+		case 2:
+			// source copy
+			sourceRel += offset(r.number())
+			if (sourceRel < 0) || (sourceRel+length > len(source)) {
+				return nil, fmt.Errorf("Invalid BPS patch, source overflow")
+			}
+
+			copy(result[out:], source[sourceRel:sourceRel+length])
+			out += length
+			sourceRel += length
+
+		case 3:
+			// target copy, byte by byte as ranges can overlap
+			targetRel += offset(r.number())
+			if (targetRel < 0) || (targetRel >= out) {
+				return nil, fmt.Errorf("Invalid BPS patch, target overflow")
+			}
+
+			for i := 0; i < length; i++ {
+				result[out] = result[targetRel]
+				out++
+				targetRel++
+			}
+		}
+	}
+
+	return result, r.err
+}
+
+// offset decodes a signed BPS relative offset
+func offset(n int) int {
+	if n&1 != 0 {
+		return -(n >> 1)
+	}
+
+	return n >> 1
+}
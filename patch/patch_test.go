@@ -0,0 +1,189 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// number encodes a BPS and UPS variable length integer
+func number(n int) []byte {
+	result := []byte{}
+
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+
+		if n == 0 {
+			return append(result, 0x80|x)
+		}
+
+		result = append(result, x)
+		n--
+	}
+}
+
+// withFooter appends BPS and UPS checksums to given patch data
+func withFooter(data []byte, source []byte, target []byte) []byte {
+	footer := make([]byte, 4)
+
+	for _, crc := range []uint32{crc32.ChecksumIEEE(source), crc32.ChecksumIEEE(target)} {
+		binary.LittleEndian.PutUint32(footer, crc)
+		data = append(data, footer...)
+	}
+
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(data))
+
+	return append(data, footer...)
+}
+
+func TestApplyIPS(t *testing.T) {
+	source := []byte("HELLO WORLD")
+
+	data := []byte("PATCH")
+	// "J" at offset 0
+	data = append(data, 0x00, 0x00, 0x00, 0x00, 0x01, 'J')
+	// RLE: three "!" at offset 11, growing data
+	data = append(data, 0x00, 0x00, 0x0B, 0x00, 0x00, 0x00, 0x03, '!')
+	data = append(data, []byte("EOF")...)
+
+	p, err := Parse(data, FormatIPS)
+	if err != nil {
+		t.Fatalf("Failed to parse IPS patch: %v", err)
+	}
+
+	if _, ok := p.SourceCRC(); ok {
+		t.Errorf("IPS patch should not have a source checksum")
+	}
+
+	result, err := p.Apply(source)
+	if err != nil {
+		t.Fatalf("Failed to apply IPS patch: %v", err)
+	}
+
+	if string(result) != "JELLO WORLD!!!" {
+		t.Errorf("IPS patch failed, got '%s'", result)
+	}
+
+	// truncation extension
+	p, _ = Parse(append(data, 0x00, 0x00, 0x05), FormatIPS)
+	if result, _ := p.Apply(source); string(result) != "JELLO" {
+		t.Errorf("IPS patch truncation failed, got '%s'", result)
+	}
+
+	p, _ = Parse([]byte("PATCH\x00\x00\x00\x00\x05AB"), FormatIPS)
+	if _, err := p.Apply(source); err == nil {
+		t.Errorf("Truncated IPS patch should have failed")
+	}
+}
+
+func TestApplyUPS(t *testing.T) {
+	source := []byte("HELLO WORLD")
+	target := []byte("HELLO WORMS")
+
+	data := append([]byte("UPS1"), number(len(source))...)
+	data = append(data, number(len(target))...)
+	data = append(data, number(9)...)
+	data = append(data, 'L'^'M', 'D'^'S', 0x00)
+	data = withFooter(data, source, target)
+
+	p, err := Parse(data, FormatUPS)
+	if err != nil {
+		t.Fatalf("Failed to parse UPS patch: %v", err)
+	}
+
+	result, err := p.Apply(source)
+	if err != nil {
+		t.Fatalf("Failed to apply UPS patch: %v", err)
+	}
+
+	if !bytes.Equal(result, target) {
+		t.Errorf("UPS patch failed, got '%s'", result)
+	}
+
+	if _, err := p.Apply([]byte("HELLO THERE")); err == nil {
+		t.Errorf("UPS patch should have failed on source checksum mismatch")
+	}
+}
+
+func TestApplyBPS(t *testing.T) {
+	source := []byte("HELLO WORLD")
+	target := []byte("HELLO HELLO WORLD!!!!")
+
+	// command is encoded with length in upper bits
+	action := func(command int, length int) []byte {
+		return number(((length - 1) << 2) | command)
+	}
+
+	data := append([]byte("BPS1"), number(len(source))...)
+	data = append(data, number(len(target))...)
+	data = append(data, number(0)...)
+	// "HELLO " from source
+	data = append(data, action(0, 6)...)
+	// "HELLO WORLD" from source offset 0
+	data = append(data, action(2, 11)...)
+	data = append(data, number(0)...)
+	// "!" from patch
+	data = append(data, action(1, 1)...)
+	data = append(data, '!')
+	// "!!!" from target offset 17, overlapping
+	data = append(data, action(3, 3)...)
+	data = append(data, number(17<<1)...)
+	data = withFooter(data, source, target)
+
+	p, err := Parse(data, FormatBPS)
+	if err != nil {
+		t.Fatalf("Failed to parse BPS patch: %v", err)
+	}
+
+	if crc, ok := p.SourceCRC(); !ok || (crc != crc32.ChecksumIEEE(source)) {
+		t.Errorf("BPS source checksum failed, got %08X", crc)
+	}
+
+	result, err := p.Apply(source)
+	if err != nil {
+		t.Fatalf("Failed to apply BPS patch: %v", err)
+	}
+
+	if !bytes.Equal(result, target) {
+		t.Errorf("BPS patch failed, got '%s'", result)
+	}
+
+	data[10]++
+	if _, err := Parse(data, FormatBPS); err == nil {
+		t.Errorf("Corrupted BPS patch should have failed")
+	}
+}
+
+func TestLoadIPSKeyedByCRC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-patches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := []byte("HELLO WORLD")
+	filePath := path.Join(dir, fmt.Sprintf("%08X [T-En].ips", crc32.ChecksumIEEE(source)))
+
+	if err := ioutil.WriteFile(filePath, []byte("PATCH\x00\x00\x00\x00\x01JEOF"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Failed to load IPS patch: %v", err)
+	}
+
+	if result, err := p.Apply(source); (err != nil) || (string(result) != "JELLO WORLD") {
+		t.Errorf("IPS patch failed, got '%s' (%v)", result, err)
+	}
+
+	if _, err := p.Apply([]byte("HELLO THERE")); err == nil {
+		t.Errorf("IPS patch keyed by CRC32 should have failed on source checksum mismatch")
+	}
+}
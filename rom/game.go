@@ -1,6 +1,11 @@
 package rom
 
-import "sort"
+import (
+	"sort"
+	"strings"
+
+	"github.com/aymerick/charette/core"
+)
 
 // Game represents a game with multiple versions
 type Game struct {
@@ -8,6 +13,12 @@ type Game struct {
 	Roms []*Rom
 
 	Moved bool
+
+	// roms moved to output directory
+	Selected []*Rom
+
+	// patched copies written next to selected roms in output directory
+	Patched []*Rom
 }
 
 // NewGame instanciates a new Game
@@ -20,6 +31,16 @@ func (g *Game) String() string {
 	return g.Name
 }
 
+// Key returns the normalized game name, used to group roms and to lookup games
+func (g *Game) Key() string {
+	return NormalizeTitle(g.Name)
+}
+
+// DisplayName returns the human friendly game name
+func (g *Game) DisplayName() string {
+	return DisplayName(g.Name)
+}
+
 // AddRom adds a new game version
 func (g *Game) AddRom(r *Rom) *Rom {
 	if g.Name == "" {
@@ -31,6 +52,22 @@ func (g *Game) AddRom(r *Rom) *Rom {
 	return r
 }
 
+// OutputRoms returns all roms written to output directory: selected roms, then their patched copies
+func (g *Game) OutputRoms() []*Rom {
+	return append(append([]*Rom{}, g.Selected...), g.Patched...)
+}
+
+// FindRom returns the rom with given file name, or nil if not found
+func (g *Game) FindRom(fileName string) *Rom {
+	for _, r := range g.Roms {
+		if r.Filename == fileName {
+			return r
+		}
+	}
+
+	return nil
+}
+
 // sortRoms sorts roms given preferred regions
 func (g *Game) sortRoms(regions []string) {
 	sort.Sort(g.NewRomsSort(regions))
@@ -38,7 +75,17 @@ func (g *Game) sortRoms(regions []string) {
 
 // BestRom returns the best rom given preferred regions, or nil if no rom matches
 func (g *Game) BestRom(regions []string) *Rom {
-	g.sortRoms(regions)
+	return g.RankedBestRom(NewRanking(regions))
+}
+
+// BestRoms returns the best rom given preferred regions, along with all other parts of the same release when that rom is part of a multi-part release
+func (g *Game) BestRoms(regions []string) []*Rom {
+	return g.RankedBestRoms(NewRanking(regions))
+}
+
+// RankedBestRom returns the best rom given ranking preferences, or nil if no rom matches. Roms that tie keep their order.
+func (g *Game) RankedBestRom(rk *Ranking) *Rom {
+	sort.Stable(g.NewRankedSort(rk))
 
 	if len(g.Roms) > 0 {
 		return g.Roms[0]
@@ -47,21 +94,124 @@ func (g *Game) BestRom(regions []string) *Rom {
 	return nil
 }
 
+// RankedBestRoms returns the best rom given ranking preferences, along with the best rom of each other part of the same release when that rom is part of a multi-part release
+func (g *Game) RankedBestRoms(rk *Ranking) []*Rom {
+	best := g.RankedBestRom(rk)
+	if best == nil {
+		return nil
+	}
+
+	if best.Part == "" {
+		return []*Rom{best}
+	}
+
+	result := []*Rom{}
+	parts := map[string]bool{}
+
+	// roms are sorted, so the first rom found for a part is the best one
+	release := best.ReleaseTitle()
+	for _, r := range g.Roms {
+		if (r.Part != "") && !parts[r.Part] && (r.ReleaseTitle() == release) {
+			parts[r.Part] = true
+			result = append(result, r)
+		}
+	}
+
+	sort.Sort(romsByPart(result))
+
+	return result
+}
+
+// romsByPart sorts roms of a multi-part release
+type romsByPart []*Rom
+
+// Implements sort.Interface
+func (a romsByPart) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a romsByPart) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a romsByPart) Less(i, j int) bool {
+	return partLess(a[i].Part, a[j].Part)
+}
+
+// partLess returns true if part a comes before part b. Numbers are compared numerically, so that "Disk 2" comes before "Disk 10", and other characters are compared as strings.
+func partLess(a string, b string) bool {
+	for (a != "") && (b != "") {
+		na, nb := leadingDigits(a), leadingDigits(b)
+
+		if (na != "") && (nb != "") {
+			// compare numbers without leading zeros, a longer number is bigger
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+
+			if ta != tb {
+				return ta < tb
+			}
+
+			a, b = a[len(na):], b[len(nb):]
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the digits at the start of given string
+func leadingDigits(str string) string {
+	i := 0
+	for (i < len(str)) && (str[i] >= '0') && (str[i] <= '9') {
+		i++
+	}
+
+	return str[:i]
+}
+
 //
 // Sort
 //
 
+// rules used to rank roms
+const (
+	RuleNone     = ""
+	RuleRegion   = "region"
+	RuleCoverage = "region coverage"
+	RuleVideo    = "video standard"
+	RulePatch    = "patch"
+	RuleAltTag   = "alternative tag"
+	RuleVersion  = "version"
+)
+
 // GameRomsSort represents a game with sorted regions
 type GameRomsSort struct {
-	Game    *Game
-	Regions []string
+	*Ranking
+
+	Game *Game
 }
 
 // NewRomsSort instanciates a new GameRomsSort
 func (g *Game) NewRomsSort(regions []string) *GameRomsSort {
+	return g.NewRankedSort(NewRanking(regions))
+}
+
+// NewRankedSort instanciates a new GameRomsSort with given ranking preferences
+func (g *Game) NewRankedSort(rk *Ranking) *GameRomsSort {
 	return &GameRomsSort{
+		Ranking: rk,
 		Game:    g,
-		Regions: regions,
 	}
 }
 
@@ -77,25 +227,75 @@ func (gs GameRomsSort) Swap(i, j int) {
 
 // Implements sort.Interface
 func (gs GameRomsSort) Less(i, j int) bool {
-	r1 := gs.Game.Roms[i]
-	r2 := gs.Game.Roms[j]
+	less, _ := gs.Compare(gs.Game.Roms[i], gs.Game.Roms[j])
+	return less
+}
+
+// Compare returns true if r1 ranks before r2, with the rule that decided. The rule is RuleNone if both roms rank the same.
+func (gs GameRomsSort) Compare(r1, r2 *Rom) (bool, string) {
+	// video - preferred video standard first
+	if gs.VideoFirst {
+		if less, decided := gs.compareVideo(r1, r2); decided {
+			return less, RuleVideo
+		}
+	}
 
-	b1 := r1.BestRegionIndex(gs.Regions)
-	b2 := r2.BestRegionIndex(gs.Regions)
+	b1, e1 := gs.RegionMatch(r1)
+	b2, e2 := gs.RegionMatch(r2)
 
 	if b1 != b2 {
-		return b1 < b2
+		return b1 < b2, RuleRegion
+	}
+
+	// region - an exact match wins over a sub region or parent region match
+	if e1 != e2 {
+		return e1, RuleRegion
+	}
+
+	// coverage - a rom matching more preferred regions wins
+	if gs.Scoring == core.RegionScoreCoverage {
+		if c1, c2 := gs.Coverage(r1), gs.Coverage(r2); c1 != c2 {
+			return c1 > c2, RuleCoverage
+		}
+	}
+
+	// video - preferred video standard first
+	if !gs.VideoFirst {
+		if less, decided := gs.compareVideo(r1, r2); decided {
+			return less, RuleVideo
+		}
+	}
+
+	// patch - a rom with a patch, eg: a fan translation, wins
+	if !gs.IgnorePatches && ((r1.Patch != "") != (r2.Patch != "")) {
+		return r1.Patch != "", RulePatch
 	}
 
 	// tag - any alternative tag is a looser
 	if r1.HaveAltTag() != r2.HaveAltTag() {
-		return r2.HaveAltTag()
+		return r2.HaveAltTag(), RuleAltTag
 	}
 
 	// version - latest version is the winner
 	if r1.Version != r2.Version {
-		return r1.Version > r2.Version
+		return r1.Version > r2.Version, RuleVersion
+	}
+
+	return false, RuleNone
+}
+
+// compareVideo returns true if r1 matches preferred video standard but r2 doesn't. The second value is false if video standard does not decide.
+func (gs GameRomsSort) compareVideo(r1, r2 *Rom) (bool, bool) {
+	if gs.Video == "" {
+		return false, false
+	}
+
+	v1 := r1.Video == gs.Video
+	v2 := r2.Video == gs.Video
+
+	if v1 == v2 {
+		return false, false
 	}
 
-	return false
+	return v1, true
 }
@@ -2,7 +2,10 @@ package rom
 
 import (
 	"fmt"
+	"sort"
 	"testing"
+
+	"github.com/aymerick/charette/core"
 )
 
 func TestGameBestRom(t *testing.T) {
@@ -20,6 +23,111 @@ func TestGameBestRom(t *testing.T) {
 	}
 }
 
+func TestGameBestRoms(t *testing.T) {
+	g := NewGame()
+	g.AddRom(MustFill("Policenauts (Japan) (Disc 1).zip"))
+	r2 := g.AddRom(MustFill("Policenauts (Japan) (Disc 2) (Rev 1).zip"))
+	g.AddRom(MustFill("Policenauts (Japan) (Disc 2).zip"))
+	r4 := g.AddRom(MustFill("Policenauts (Japan) (Disc 1) (Rev 1).zip"))
+	r5 := g.AddRom(MustFill("Policenauts (Japan) (Disc 3) (Rev 1).zip"))
+
+	regions := []string{"Europe", "USA", "Japan"}
+
+	expected := []*Rom{r4, r2, r5}
+
+	roms := g.BestRoms(regions)
+	if len(roms) != len(expected) {
+		t.Fatal(fmt.Sprintf("Game best roms computation failed\n\tgot     : %v\n\texpected: %v", roms, expected))
+	}
+
+	for i, rom := range roms {
+		if rom != expected[i] {
+			t.Fatal(fmt.Sprintf("Game best roms computation failed\n\tgot     : %v\n\texpected: %v", roms, expected))
+		}
+	}
+}
+
+func TestGameBestRomsParts(t *testing.T) {
+	g := NewGame()
+	for i := 12; i >= 1; i-- {
+		g.AddRom(MustFill(fmt.Sprintf("Dungeon Master (Europe) (Disk %d of 12).adf", i)))
+	}
+
+	roms := g.BestRoms([]string{"Europe"})
+	if len(roms) != 12 {
+		t.Fatalf("Game best roms computation failed, got %d parts but expected 12", len(roms))
+	}
+
+	for i, r := range roms {
+		if expected := fmt.Sprintf("Disk %d of 12", i+1); r.Part != expected {
+			t.Errorf("Game parts sort failed, got '%s' at position %d but expected '%s'", r.Part, i+1, expected)
+		}
+	}
+}
+
+func TestGameBestRomsRevisedParts(t *testing.T) {
+	tests := []struct {
+		fileNames []string
+		expected  []string
+	}{
+		// only one disc was revised
+		{
+			[]string{"Final Fantasy VII (Europe) (Disc 1).zip", "Final Fantasy VII (Europe) (Disc 2).zip", "Final Fantasy VII (Europe) (Disc 2) (Rev 1).zip", "Final Fantasy VII (Europe) (Disc 3).zip", "Final Fantasy VII (USA) (Disc 1).zip"},
+			[]string{"Final Fantasy VII (Europe) (Disc 1).zip", "Final Fantasy VII (Europe) (Disc 2) (Rev 1).zip", "Final Fantasy VII (Europe) (Disc 3).zip"},
+		},
+		// revised disc is missing the original
+		{
+			[]string{"Final Fantasy VII (Europe) (Disc 2) (Rev 1).zip", "Final Fantasy VII (Europe) (Disc 1).zip"},
+			[]string{"Final Fantasy VII (Europe) (Disc 1).zip", "Final Fantasy VII (Europe) (Disc 2) (Rev 1).zip"},
+		},
+		// beta discs are another release
+		{
+			[]string{"Final Fantasy VII (Europe) (Disc 1).zip", "Final Fantasy VII (Europe) (Disc 2) (Beta).zip"},
+			[]string{"Final Fantasy VII (Europe) (Disc 1).zip"},
+		},
+	}
+
+	for _, test := range tests {
+		g := NewGame()
+		for _, fileName := range test.fileNames {
+			g.AddRom(MustFill(fileName))
+		}
+
+		result := []string{}
+		for _, r := range g.BestRoms([]string{"Europe"}) {
+			result = append(result, r.Filename)
+		}
+
+		if !testEq(result, test.expected) {
+			t.Errorf("Game best roms computation failed\n\tgot     : %v\n\texpected: %v", result, test.expected)
+		}
+	}
+}
+
+func TestPartLess(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{"Disk 2", "Disk 10", true},
+		{"Disk 10", "Disk 2", false},
+		{"Disk 1", "Disk 1", false},
+		{"Disk 02", "Disk 10", true},
+		{"Disk 1 Side A", "Disk 1 Side B", true},
+		{"Disk 2 Side A", "Disk 10 Side A", true},
+		{"Side A", "Side B", true},
+		{"Disk 1", "Disk 1 Side A", true},
+		{"Disk 9 of 10", "Disk 10 of 10", true},
+	}
+
+	for _, test := range tests {
+		if result := partLess(test.a, test.b); result != test.expected {
+			t.Errorf("Failed to compare parts '%s' and '%s', got %v but expected %v", test.a, test.b, result, test.expected)
+		}
+	}
+}
+
 func TestGameRomsSort(t *testing.T) {
 	g := NewGame()
 
@@ -56,7 +164,8 @@ func TestGameRomsSort2(t *testing.T) {
 	regions := []string{"France", "Europe", "World", "USA", "Japan"}
 	g.sortRoms(regions)
 
-	expected := []*Rom{r1, r5, r4, r3, r2}
+	// germany is a sub region of europe
+	expected := []*Rom{r1, r3, r2, r5, r4}
 
 	for i, rom := range g.Roms {
 		if rom != expected[i] {
@@ -64,3 +173,234 @@ func TestGameRomsSort2(t *testing.T) {
 		}
 	}
 }
+
+func TestGameRomsSortCompare(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Donkey Kong Country 2 - Diddy's Kong Quest (USA) (Rev 1).zip"))
+	r2 := g.AddRom(MustFill("Donkey Kong Country 2 - Diddy's Kong Quest (USA).zip"))
+	r3 := g.AddRom(MustFill("Donkey Kong Country 2 - Diddy's Kong Quest (Europe) (Beta).zip"))
+	r4 := g.AddRom(MustFill("Donkey Kong Country 2 - Diddy's Kong Quest (Europe).zip"))
+
+	gs := g.NewRomsSort([]string{"Europe", "USA"})
+
+	tests := []struct {
+		r1   *Rom
+		r2   *Rom
+		less bool
+		rule string
+	}{
+		{r1, r2, true, RuleVersion},
+		{r2, r1, false, RuleVersion},
+		{r4, r1, true, RuleRegion},
+		{r4, r3, true, RuleAltTag},
+		{r2, r2, false, RuleNone},
+	}
+
+	for _, test := range tests {
+		less, rule := gs.Compare(test.r1, test.r2)
+		if (less != test.less) || (rule != test.rule) {
+			t.Errorf("Roms comparison failed for '%v' and '%v', got (%v, '%v') but expected (%v, '%v')", test.r1, test.r2, less, rule, test.less, test.rule)
+		}
+	}
+}
+
+func TestGameRomsSortSubRegions(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Asterix (France).zip"))
+	r2 := g.AddRom(MustFill("Asterix (Europe) (En,Fr,De,Es,It).zip"))
+	r3 := g.AddRom(MustFill("Asterix (USA).zip"))
+
+	tests := []struct {
+		regions  []string
+		expected []*Rom
+	}{
+		{[]string{"France", "USA"}, []*Rom{r1, r2, r3}},
+		{[]string{"Europe", "USA"}, []*Rom{r2, r1, r3}},
+		{[]string{"USA", "Europe"}, []*Rom{r3, r2, r1}},
+		{[]string{"Sweden", "USA"}, []*Rom{r2, r3, r1}},
+	}
+
+	for _, test := range tests {
+		g.sortRoms(test.regions)
+
+		for i, rom := range g.Roms {
+			if rom != test.expected[i] {
+				t.Fatal(fmt.Sprintf("Game roms sort failed for regions %v\n\tgot     : %v\n\texpected: %v", test.regions, g.Roms, test.expected))
+			}
+		}
+	}
+}
+
+func TestGameRomsSortVideo(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Mega Game (Japan).zip"))
+	r2 := g.AddRom(MustFill("Mega Game (Europe).zip"))
+	r3 := g.AddRom(MustFill("Mega Game (USA).zip"))
+	r4 := g.AddRom(MustFill("Mega Game (World).zip"))
+
+	tests := []struct {
+		rk       *Ranking
+		expected []*Rom
+	}{
+		{&Ranking{Regions: []string{"USA", "Europe"}}, []*Rom{r3, r2, r1, r4}},
+		{&Ranking{Regions: []string{"USA", "Europe"}, Video: "PAL"}, []*Rom{r3, r2, r1, r4}},
+		{&Ranking{Regions: []string{"USA", "Europe"}, Video: "PAL", VideoFirst: true}, []*Rom{r2, r3, r1, r4}},
+		{&Ranking{Regions: []string{"World"}, Video: "PAL"}, []*Rom{r4, r2, r1, r3}},
+		{&Ranking{Regions: []string{"World"}, Video: "NTSC"}, []*Rom{r4, r1, r3, r2}},
+	}
+
+	for _, test := range tests {
+		g.Roms = []*Rom{r1, r2, r3, r4}
+		sort.Stable(g.NewRankedSort(test.rk))
+
+		for i, rom := range g.Roms {
+			if rom != test.expected[i] {
+				t.Fatal(fmt.Sprintf("Game roms sort failed for ranking %v\n\tgot     : %v\n\texpected: %v", *test.rk, g.Roms, test.expected))
+			}
+		}
+
+		if best := g.RankedBestRom(test.rk); best != test.expected[0] {
+			t.Errorf("Best rom failed for ranking %v, got %v but expected %v", *test.rk, best, test.expected[0])
+		}
+	}
+}
+
+func TestGameRomsSortCoverage(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Bomberman GB (Europe) (SGB Enhanced).zip"))
+	r2 := g.AddRom(MustFill("Bomberman GB (USA, Europe) (SGB Enhanced).zip"))
+	r3 := g.AddRom(MustFill("Bomberman GB (Japan) (SGB Enhanced).zip"))
+
+	tests := []struct {
+		rk       *Ranking
+		expected []*Rom
+	}{
+		{&Ranking{Regions: []string{"Europe", "USA"}, Scoring: core.RegionScoreBest}, []*Rom{r1, r2, r3}},
+		{&Ranking{Regions: []string{"Europe", "USA"}, Scoring: core.RegionScoreCoverage}, []*Rom{r2, r1, r3}},
+		{&Ranking{Regions: []string{"USA", "Europe"}, Scoring: core.RegionScoreCoverage}, []*Rom{r2, r1, r3}},
+		{&Ranking{Regions: []string{"Japan", "Europe"}, Scoring: core.RegionScoreCoverage}, []*Rom{r3, r1, r2}},
+	}
+
+	for _, test := range tests {
+		g.Roms = []*Rom{r1, r2, r3}
+		sort.Stable(g.NewRankedSort(test.rk))
+
+		for i, rom := range g.Roms {
+			if rom != test.expected[i] {
+				t.Fatal(fmt.Sprintf("Game roms sort failed for ranking %v\n\tgot     : %v\n\texpected: %v", *test.rk, g.Roms, test.expected))
+			}
+		}
+	}
+}
+
+func TestGameRomsSortWorldAny(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Tetris (Japan) (En).zip"))
+	r2 := g.AddRom(MustFill("Tetris (World) (Rev A).zip"))
+	r3 := g.AddRom(MustFill("Tetris (France).zip"))
+
+	tests := []struct {
+		rk       *Ranking
+		expected []*Rom
+	}{
+		{&Ranking{Regions: []string{"Japan", "USA"}}, []*Rom{r1, r2, r3}},
+		{&Ranking{Regions: []string{"Japan", "USA"}, WorldAny: true}, []*Rom{r1, r2, r3}},
+		{&Ranking{Regions: []string{"USA", "Japan"}, WorldAny: true}, []*Rom{r2, r1, r3}},
+		{&Ranking{Regions: []string{"Europe", "USA"}, WorldAny: true}, []*Rom{r2, r3, r1}},
+		{&Ranking{Regions: []string{"Europe", "USA"}, WorldAny: true, Scoring: core.RegionScoreCoverage}, []*Rom{r2, r3, r1}},
+	}
+
+	for _, test := range tests {
+		g.Roms = []*Rom{r1, r2, r3}
+		sort.Stable(g.NewRankedSort(test.rk))
+
+		for i, rom := range g.Roms {
+			if rom != test.expected[i] {
+				t.Fatal(fmt.Sprintf("Game roms sort failed for ranking %v\n\tgot     : %v\n\texpected: %v", *test.rk, g.Roms, test.expected))
+			}
+		}
+	}
+}
+
+func TestGameRomsSortPatch(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Seiken Densetsu 3 (Japan) (Rev 1).zip"))
+	r2 := g.AddRom(MustFill("Seiken Densetsu 3 (Japan).zip"))
+	r3 := g.AddRom(MustFill("Seiken Densetsu 3 (USA).zip"))
+
+	r2.Patch = "Seiken Densetsu 3 (Japan) [T-En].bps"
+
+	tests := []struct {
+		regions  []string
+		expected []*Rom
+	}{
+		{[]string{"Japan"}, []*Rom{r2, r1, r3}},
+		{[]string{"USA", "Japan"}, []*Rom{r3, r2, r1}},
+	}
+
+	for _, test := range tests {
+		g.Roms = []*Rom{r1, r2, r3}
+		sort.Stable(g.NewRomsSort(test.regions))
+
+		for i, rom := range g.Roms {
+			if rom != test.expected[i] {
+				t.Fatal(fmt.Sprintf("Game roms sort failed for regions %v\n\tgot     : %v\n\texpected: %v", test.regions, g.Roms, test.expected))
+			}
+		}
+	}
+
+	if less, rule := g.NewRomsSort([]string{"Japan"}).Compare(r2, r1); !less || (rule != RulePatch) {
+		t.Errorf("Game roms compare failed, got %v with rule '%s'", less, rule)
+	}
+
+	// softpatches don't change selection
+	rk := NewRanking([]string{"Japan"})
+	rk.IgnorePatches = true
+
+	if less, rule := g.NewRankedSort(rk).Compare(r1, r2); !less || (rule != RuleVersion) {
+		t.Errorf("Game roms compare ignoring patches failed, got %v with rule '%s'", less, rule)
+	}
+}
+
+func TestRankingCoverage(t *testing.T) {
+	tests := []struct {
+		fileName string
+		worldAny bool
+		expected int
+	}{
+		{"Donkey Kong Land (USA, Europe) (SGB Enhanced).zip", false, 2},
+		{"Donkey Kong Land (Japan) (SGB Enhanced).zip", false, 0},
+		{"Donkey Kong Land (France).zip", false, 1},
+		{"Donkey Kong (World) (Rev A) (SGB Enhanced).zip", false, 0},
+		{"Donkey Kong (World) (Rev A) (SGB Enhanced).zip", true, 3},
+	}
+
+	for _, test := range tests {
+		rk := &Ranking{Regions: []string{"Europe", "USA", "Australia"}, WorldAny: test.worldAny}
+
+		if result := rk.Coverage(MustFill(test.fileName)); result != test.expected {
+			t.Errorf("Region coverage failed, got %d but expected %d: %s", result, test.expected, test.fileName)
+		}
+	}
+}
+
+func TestGameFindRom(t *testing.T) {
+	g := NewGame()
+
+	r1 := g.AddRom(MustFill("Tetris (World) (Rev A).zip"))
+	g.AddRom(MustFill("Tetris (Japan) (En).zip"))
+
+	if r := g.FindRom("Tetris (World) (Rev A).zip"); r != r1 {
+		t.Errorf("Rom lookup failed, got %v but expected %v", r, r1)
+	}
+
+	if r := g.FindRom("Tetris (World).zip"); r != nil {
+		t.Errorf("Rom lookup failed, got %v but expected nil", r)
+	}
+}
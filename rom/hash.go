@@ -0,0 +1,86 @@
+package rom
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/skipper"
+)
+
+// ComputeCRC computes and returns the CRC32 of rom data. For a zipped rom, the CRC32 of the zipped file is used. With a header skipper, the CRC32 is computed on headerless data.
+func (r *Rom) ComputeCRC() (string, error) {
+	if r.CRC != "" {
+		return r.CRC, nil
+	}
+
+	var sum uint32
+	var err error
+
+	if r.Skipper != nil {
+		sum, err = headerlessCRC(r.File, r.Skipper)
+	} else if path.Ext(r.File) == ".zip" {
+		sum, err = helpers.ZipCRC(r.File)
+	} else {
+		sum, err = fileCRC(r.File)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	r.CRC = fmt.Sprintf("%08X", sum)
+
+	return r.CRC, nil
+}
+
+// ComputeSize computes and returns the rom file size
+func (r *Rom) ComputeSize() (int64, error) {
+	if r.Size > 0 {
+		return r.Size, nil
+	}
+
+	info, err := os.Stat(r.File)
+	if err != nil {
+		return 0, err
+	}
+
+	r.Size = info.Size()
+
+	return r.Size, nil
+}
+
+// headerlessCRC returns the CRC32 of given rom file data without its header. For a zipped rom, the first file in zip archive is read.
+func headerlessCRC(filePath string, s *skipper.Detector) (uint32, error) {
+	data, _, err := helpers.ReadRom(filePath, helpers.MaxRomSize+1)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) > helpers.MaxRomSize {
+		return 0, fmt.Errorf("Rom too big to skip its header: %s", filePath)
+	}
+
+	_, data = s.Split(data)
+
+	return crc32.ChecksumIEEE(data), nil
+}
+
+// fileCRC returns the CRC32 of given file
+func fileCRC(filePath string) (uint32, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, f); err != nil {
+		return 0, err
+	}
+
+	return h.Sum32(), nil
+}
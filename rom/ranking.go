@@ -0,0 +1,81 @@
+package rom
+
+import "github.com/aymerick/charette/core"
+
+// Ranking holds the preferences used to rank roms of a game
+type Ranking struct {
+	// preferred regions
+	Regions []string
+
+	// region scoring mode: core.RegionScoreBest or core.RegionScoreCoverage
+	Scoring string
+
+	// "World" roms match any preferred region
+	WorldAny bool
+
+	// preferred video standard: core.VideoPAL, core.VideoNTSC, or empty for no preference
+	Video string
+
+	// rank video standard above regions
+	VideoFirst bool
+
+	// patches are applied by frontends at load time, so a rom with a patch does not win over other roms
+	IgnorePatches bool
+}
+
+// NewRanking instanciates a new Ranking with given preferred regions
+func NewRanking(regions []string) *Ranking {
+	return &Ranking{
+		Regions: regions,
+		Scoring: core.RegionScoreBest,
+	}
+}
+
+// RankingFor returns the ranking preferences for given options
+func RankingFor(options *core.Options) *Ranking {
+	return &Ranking{
+		Regions:       options.Regions,
+		Scoring:       options.RegionScoring,
+		WorldAny:      options.WorldAny,
+		Video:         options.Video,
+		VideoFirst:    options.VideoFirst,
+		IgnorePatches: options.PatchMode == core.PatchSoftpatch,
+	}
+}
+
+// RegionMatch computes the lowest index in preferred regions for given rom, and returns true if that region is exactly one of rom regions. With WorldAny, a "World" rom matches the first preferred region, as an implied match.
+func (rk *Ranking) RegionMatch(r *Rom) (int, bool) {
+	i, exact := r.RegionMatch(rk.Regions)
+
+	if rk.WorldAny && (i > 0) && (len(rk.Regions) > 0) && r.isWorld() {
+		return 0, false
+	}
+
+	return i, exact
+}
+
+// HaveRegion returns true if given rom matches a preferred region
+func (rk *Ranking) HaveRegion(r *Rom) bool {
+	i, _ := rk.RegionMatch(r)
+	return i < len(rk.Regions)
+}
+
+// Coverage returns the number of preferred regions matched by given rom regions. With WorldAny, a "World" rom covers all preferred regions.
+func (rk *Ranking) Coverage(r *Rom) int {
+	if rk.WorldAny && r.isWorld() {
+		return len(rk.Regions)
+	}
+
+	result := 0
+
+	for _, region := range rk.Regions {
+		for _, romRegion := range r.Regions {
+			if (romRegion == region) || core.Related(romRegion, region) {
+				result++
+				break
+			}
+		}
+	}
+
+	return result
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/skipper"
 )
 
 const (
@@ -24,6 +25,12 @@ var rDemo = regexp.MustCompile(`\(([^\(]*)Demo([^\(]*)\)`)
 var rPirate = regexp.MustCompile(`\(([^\(]*)Pirate([^\(]*)\)`)
 var rPromo = regexp.MustCompile(`\(([^\(]*)Promo([^\(]*)\)`)
 
+var rLanguages = regexp.MustCompile(`\(((?:[A-Z][a-z](?:-[A-Z][a-z])?,)*[A-Z][a-z](?:-[A-Z][a-z])?)\)`)
+
+var rVideo = regexp.MustCompile(`\((PAL|NTSC)\)`)
+
+var rPart = regexp.MustCompile(`\(((?:Disc|Disk|Side|Part|Tape) [^\(\)]*)\)`)
+
 // Rom represents a game version
 type Rom struct {
 	File     string
@@ -32,6 +39,30 @@ type Rom struct {
 	Regions  []string
 	Version  string
 
+	// languages codes, eg: ["En", "Fr", "Es"]
+	Languages []string
+
+	// video standard, from explicit "(PAL)" or "(NTSC)" tag or from regions, empty if unknown
+	Video string
+
+	// CRC32 of rom data, computed on demand by ComputeCRC()
+	CRC string
+
+	// CRC32 of the original no-intro dump when rom data was converted, eg: to another N64 byte order, empty otherwise
+	DumpCRC string
+
+	// header skipper of rom system, nil if none
+	Skipper *skipper.Detector
+
+	// path of the patch to apply to that rom, eg: a fan translation, empty if none
+	Patch string
+
+	// file size, computed on demand by ComputeSize()
+	Size int64
+
+	// disc, disk side, part or tape of a multi-part release, eg: "Disc 1"
+	Part string
+
 	Proto  bool
 	Beta   bool
 	Bios   bool
@@ -103,6 +134,34 @@ func (r *Rom) String() string {
 		result += " (" + r.Version + ")"
 	}
 
+	if r.Part != "" {
+		result += " (" + r.Part + ")"
+	}
+
+	return result
+}
+
+// ReleaseName returns the file name without extension and without part tag, so that all parts of a release share the same release name
+func (r *Rom) ReleaseName() string {
+	result := r.Filename[:len(r.Filename)-len(path.Ext(r.Filename))]
+
+	if r.Part != "" {
+		result = strings.Replace(result, "("+r.Part+")", "", 1)
+		result = strings.Join(strings.Fields(result), " ")
+	}
+
+	return result
+}
+
+// ReleaseTitle returns the release name without version tag, so that all parts of a release share the same title even when only some parts were revised, eg: "X (Europe) (Disc 1)" and "X (Europe) (Disc 2) (Rev 1)"
+func (r *Rom) ReleaseTitle() string {
+	result := r.ReleaseName()
+
+	if rVersion.MatchString(result) {
+		result = rVersion.ReplaceAllString(result, "")
+		result = strings.Join(strings.Fields(result), " ")
+	}
+
 	return result
 }
 
@@ -119,7 +178,7 @@ func NameAndRegions(fileName string) (string, []string) {
 
 	for _, tag := range tags {
 		// check tag (don't forget to remove parenthesis)
-		regions = core.ExtractRegions(fileName[tag[0]+1 : tag[1]-1])
+		regions = core.ParseRegions(fileName[tag[0]+1 : tag[1]-1])
 		if len(regions) > 0 {
 			regionsIndex = tag[0]
 			break
@@ -143,6 +202,9 @@ func (r *Rom) Fill() error {
 	r.Name, r.Regions = NameAndRegions(r.Filename)
 
 	r.Version = r.extractVersion()
+	r.Languages = r.extractLanguages()
+	r.Video = r.extractVideo()
+	r.Part = r.extractPart()
 
 	r.Proto = rProto.MatchString(r.Filename)
 	r.Beta = rBeta.MatchString(r.Filename)
@@ -155,35 +217,66 @@ func (r *Rom) Fill() error {
 	return nil
 }
 
-// HaveRegion returns true if rom matches with given regions
+// HaveRegion returns true if rom matches with given regions, sub regions and parent regions match too
 func (r *Rom) HaveRegion(regions []string) bool {
-	for _, region := range regions {
-		if indexOf(r.Regions, region) != -1 {
-			return true
+	return r.BestRegionIndex(regions) < len(regions)
+}
+
+// BestRegionIndex computes the lowest index in given regions list for that rom. A region matches its sub regions and its parent regions, eg: "Europe" matches with a "(France)" rom and "France" matches with a "(Europe)" rom.
+func (r *Rom) BestRegionIndex(regions []string) int {
+	result, _ := r.RegionMatch(regions)
+	return result
+}
+
+// RegionMatch computes the lowest index in given regions list for that rom, and returns true if that region is exactly one of rom regions, or false if the match is implied by a sub region or a parent region
+func (r *Rom) RegionMatch(regions []string) (int, bool) {
+	for i, region := range regions {
+		exact := false
+		implied := false
+
+		for _, romRegion := range r.Regions {
+			if romRegion == region {
+				exact = true
+			} else if core.Related(romRegion, region) {
+				implied = true
+			}
+		}
+
+		if exact || implied {
+			return i, exact
 		}
 	}
 
-	return false
+	return len(regions), false
 }
 
-// BestRegionIndex computes the lowest index in given regions list for that rom
-func (r *Rom) BestRegionIndex(regions []string) int {
-	result := len(regions)
-
+// isWorld returns true if that rom is tagged with the "World" region
+func (r *Rom) isWorld() bool {
 	for _, region := range r.Regions {
-		if i := indexOf(regions, region); (i != -1) && (i < result) {
-			result = i
+		if region == "World" {
+			return true
 		}
 	}
 
-	return result
+	return false
 }
 
-// BestRegion returns the best region name in given regions list for that rom
+// BestRegion returns the region of that rom that best matches given regions list, to be displayed: a preferred region found in rom regions, or else the rom region that implies a preferred region, eg: "Europe" for an "(Europe)" rom when "France" is preferred. Implied matches are only used to rank roms, so they are never reported as the rom region.
 func (r *Rom) BestRegion(regions []string) string {
-	i := r.BestRegionIndex(regions)
-	if i < len(regions) {
-		return regions[i]
+	for _, region := range regions {
+		for _, romRegion := range r.Regions {
+			if romRegion == region {
+				return region
+			}
+		}
+	}
+
+	for _, region := range regions {
+		for _, romRegion := range r.Regions {
+			if core.Related(romRegion, region) {
+				return romRegion
+			}
+		}
 	}
 
 	return r.Regions[0]
@@ -210,14 +303,46 @@ func (r *Rom) extractVersion() string {
 	return result
 }
 
-// @todo Move that to a 'utils' package
-func indexOf(ar []string, value string) int {
-	for i, v := range ar {
-		if v == value {
-			return i
+func (r *Rom) extractLanguages() []string {
+	match := rLanguages.FindStringSubmatch(r.Filename)
+	if len(match) == 2 {
+		return strings.Split(match[1], ",")
+	}
+
+	return []string{}
+}
+
+func (r *Rom) extractVideo() string {
+	// explicit tag
+	match := rVideo.FindStringSubmatch(r.Filename)
+	if len(match) == 2 {
+		return match[1]
+	}
+
+	// all regions must share the same video standard
+	result := ""
+
+	for _, region := range r.Regions {
+		video := core.RegionVideo(region)
+		if video == "" {
+			continue
+		}
+
+		if (result != "") && (result != video) {
+			return ""
 		}
+
+		result = video
+	}
+
+	return result
+}
+
+func (r *Rom) extractPart() string {
+	match := rPart.FindStringSubmatch(r.Filename)
+	if len(match) == 2 {
+		return match[1]
 	}
 
-	// not found
-	return -1
+	return ""
 }
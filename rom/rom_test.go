@@ -179,6 +179,105 @@ func TestRomFill(t *testing.T) {
 	}
 }
 
+var partTests = []struct {
+	fileName string
+	part     string
+	release  string
+	title    string
+}{
+	{"Final Fantasy VII (Europe) (Disc 1).zip", "Disc 1", "Final Fantasy VII (Europe)", "Final Fantasy VII (Europe)"},
+	{"Final Fantasy VII (Europe) (Disc 2) (Rev 1).zip", "Disc 2", "Final Fantasy VII (Europe) (Rev 1)", "Final Fantasy VII (Europe)"},
+	{"Zelda no Densetsu - The Hyrule Fantasy (Japan) (Disk 1 Side A).zip", "Disk 1 Side A", "Zelda no Densetsu - The Hyrule Fantasy (Japan)", "Zelda no Densetsu - The Hyrule Fantasy (Japan)"},
+	{"Monkey Island 2 (Europe) (Side B).zip", "Side B", "Monkey Island 2 (Europe)", "Monkey Island 2 (Europe)"},
+	{"Gain Ground (World) (Rev A).zip", "", "Gain Ground (World) (Rev A)", "Gain Ground (World)"},
+}
+
+var languagesTests = []struct {
+	fileName  string
+	languages []string
+}{
+	{"Captain Novolin (USA) (En,Fr,Es).zip", []string{"En", "Fr", "Es"}},
+	{"Adventures of Dr. Franken, The (Europe) (En,Fr,De,Es,It,Nl,Sv)", []string{"En", "Fr", "De", "Es", "It", "Nl", "Sv"}},
+	{"Tetris (Japan) (En).zip", []string{"En"}},
+	{"Gain Ground (World) (Rev A).zip", []string{}},
+	{"Bubsy in Claws Encounters of the Furred Kind (USA) (Beta 1).zip", []string{}},
+}
+
+var videoTests = []struct {
+	fileName string
+	video    string
+}{
+	{"Sonic The Hedgehog (Europe).zip", "PAL"},
+	{"Sonic The Hedgehog (USA, Europe).zip", ""},
+	{"Sonic The Hedgehog (Japan, Korea).zip", "NTSC"},
+	{"Sonic The Hedgehog (France).zip", "PAL"},
+	{"Sonic The Hedgehog (Brazil).zip", "NTSC"},
+	{"Sonic The Hedgehog (World).zip", ""},
+	{"Sonic The Hedgehog (Brazil) (PAL).zip", "PAL"},
+	{"Sonic The Hedgehog (World) (NTSC).zip", "NTSC"},
+}
+
+func TestRomLanguages(t *testing.T) {
+	for _, test := range languagesTests {
+		rom := MustFill(test.fileName)
+
+		if !testEq(rom.Languages, test.languages) {
+			t.Errorf("Languages extraction failed, got '%v' but expected '%v': %s", rom.Languages, test.languages, test.fileName)
+		}
+	}
+}
+
+func TestRomVideo(t *testing.T) {
+	for _, test := range videoTests {
+		rom := MustFill(test.fileName)
+
+		if rom.Video != test.video {
+			t.Errorf("Video standard extraction failed, got '%v' but expected '%v': %s", rom.Video, test.video, test.fileName)
+		}
+	}
+}
+
+func TestRomPart(t *testing.T) {
+	for _, test := range partTests {
+		rom := MustFill(test.fileName)
+
+		if rom.Part != test.part {
+			t.Errorf("Part extraction failed, got '%v' but expected '%v': %s", rom.Part, test.part, test.fileName)
+		}
+
+		if rom.ReleaseName() != test.release {
+			t.Errorf("Release name computation failed, got '%v' but expected '%v': %s", rom.ReleaseName(), test.release, test.fileName)
+		}
+
+		if rom.ReleaseTitle() != test.title {
+			t.Errorf("Release title computation failed, got '%v' but expected '%v': %s", rom.ReleaseTitle(), test.title, test.fileName)
+		}
+	}
+}
+
+func TestRomBestRegion(t *testing.T) {
+	tests := []struct {
+		fileName string
+		regions  []string
+		expected string
+	}{
+		// implied match reports the rom region
+		{"Tetris (Europe).gb", []string{"France", "Europe", "USA"}, "Europe"},
+		{"Tetris (France).gb", []string{"Europe", "USA"}, "France"},
+		// exact match wins over an implied one
+		{"Tetris (Europe, France).gb", []string{"France", "Europe"}, "France"},
+		{"Tetris (USA, Europe).gb", []string{"France", "USA"}, "USA"},
+		// no match
+		{"Tetris (Japan).gb", []string{"France", "Europe"}, "Japan"},
+	}
+
+	for _, test := range tests {
+		if result := MustFill(test.fileName).BestRegion(test.regions); result != test.expected {
+			t.Errorf("Best region computation failed with regions %v, got '%s' but expected '%s': %s", test.regions, result, test.expected, test.fileName)
+		}
+	}
+}
+
 func testEq(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,70 @@
+package rom
+
+import (
+	"strings"
+	"unicode"
+)
+
+// articles that no-intro moves at the end of titles, eg: "Legend of Zelda, The"
+var articles = []string{
+	"The", "A", "An",
+	"Le", "La", "Les", "L'",
+	"Der", "Die", "Das",
+	"El", "Los", "Las",
+	"Il", "Lo", "Gli",
+	"De", "Het",
+}
+
+// DisplayName returns the human friendly version of given game name, with trailing articles moved to the front
+//
+// eg: "Addams Family, The - Pugsley's Scavenger Hunt" => "The Addams Family - Pugsley's Scavenger Hunt"
+func DisplayName(name string) string {
+	parts := strings.Split(name, " - ")
+
+	for i, part := range parts {
+		parts[i] = moveArticle(part)
+	}
+
+	return strings.Join(parts, " - ")
+}
+
+// NormalizeTitle returns the matching key for given game name: articles are moved, "&" is folded to "and", punctuation is stripped and case is lowered
+//
+// eg: "Legend of Zelda, The - Link's Awakening DX" => "the legend of zelda links awakening dx"
+func NormalizeTitle(name string) string {
+	name = strings.ToLower(DisplayName(name))
+	name = strings.Replace(name, "&", " and ", -1)
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return r
+		}
+
+		if r == '-' || r == '_' || r == ':' || r == '/' {
+			return ' '
+		}
+
+		// strip punctuation
+		return -1
+	}, name)
+
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// moveArticle moves a trailing article to the front of given title
+func moveArticle(title string) string {
+	for _, article := range articles {
+		suffix := ", " + article
+		if strings.HasSuffix(title, suffix) {
+			title = title[:len(title)-len(suffix)]
+
+			if strings.HasSuffix(article, "'") {
+				return article + title
+			}
+
+			return article + " " + title
+		}
+	}
+
+	return title
+}
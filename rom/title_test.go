@@ -0,0 +1,56 @@
+package rom
+
+import "testing"
+
+var titleTests = []struct {
+	name       string
+	display    string
+	normalized string
+}{
+	{
+		"Legend of Zelda, The - A Link to the Past",
+		"The Legend of Zelda - A Link to the Past",
+		"the legend of zelda a link to the past",
+	},
+	{
+		"Addams Family, The - Pugsley's Scavenger Hunt",
+		"The Addams Family - Pugsley's Scavenger Hunt",
+		"the addams family pugsleys scavenger hunt",
+	},
+	{
+		"Ren & Stimpy Show, The - Buckeroo$!",
+		"The Ren & Stimpy Show - Buckeroo$!",
+		"the ren and stimpy show buckeroo",
+	},
+	{
+		"Mickey Mania - The Timeless Adventures of Mickey Mouse",
+		"Mickey Mania - The Timeless Adventures of Mickey Mouse",
+		"mickey mania the timeless adventures of mickey mouse",
+	},
+	{
+		"Aventure de Rayman, L'",
+		"L'Aventure de Rayman",
+		"laventure de rayman",
+	},
+	{
+		"Dr. Mario",
+		"Dr. Mario",
+		"dr mario",
+	},
+}
+
+func TestTitle(t *testing.T) {
+	for _, test := range titleTests {
+		if result := DisplayName(test.name); result != test.display {
+			t.Errorf("Display name computation failed, got '%v' but expected '%v'", result, test.display)
+		}
+
+		if result := NormalizeTitle(test.name); result != test.normalized {
+			t.Errorf("Title normalization failed, got '%v' but expected '%v'", result, test.normalized)
+		}
+	}
+
+	if NormalizeTitle("TETRIS") != NormalizeTitle("Tetris") {
+		t.Errorf("Title normalization must be case insensitive")
+	}
+}
@@ -0,0 +1,398 @@
+package romheader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// N64 byte orders
+const (
+	// big endian, native order
+	N64BigEndian = "z64"
+
+	// byte swapped
+	N64ByteSwapped = "v64"
+
+	// little endian
+	N64LittleEndian = "n64"
+)
+
+// Header represents the internal header of a rom
+type Header struct {
+	// system, as "<Manufacturer> - <Name>"
+	System string
+
+	// header format, eg: "iNES", "NES 2.0", "LoROM", "HiROM" or a N64 byte order
+	Format string
+
+	// internal title
+	Title string
+
+	// region guessed from header, empty if unknown or ambiguous
+	Region string
+
+	// problems found in header, eg: bad checksums
+	Errors []string
+}
+
+// Systems returns the systems which headers can be parsed
+func Systems() []string {
+	return []string{SystemNES, SystemSNES, SystemGB, SystemGBC, SystemGBA, SystemN64, SystemMegaDrive, System32X}
+}
+
+// Parse reads the internal header of given rom data, and returns nil if no supported header was found. Data must contain the whole rom, as checksums are verified.
+func Parse(data []byte) *Header {
+	switch {
+	case bytes.HasPrefix(data, []byte("NES\x1a")):
+		return parseNES(data)
+
+	case isN64(data):
+		return parseN64(data)
+
+	case hasAt(data, 0x104, gbLogo):
+		return parseGB(data)
+
+	case hasAt(data, 0x04, gbaLogo):
+		return parseGBA(data)
+
+	case hasAt(data, 0x100, []byte("SEGA")):
+		return parseMegaDrive(data)
+	}
+
+	if offset, copier := snesHeader(data); offset >= 0 {
+		return parseSNES(data, offset, copier)
+	}
+
+	return nil
+}
+
+// String implements fmt.Stringer
+func (h *Header) String() string {
+	result := fmt.Sprintf("%s [%s] '%s'", h.System, h.Format, h.Title)
+	if h.Region != "" {
+		result += " " + h.Region
+	}
+
+	return result
+}
+
+// Valid returns true if no problem was found in header
+func (h *Header) Valid() bool {
+	return len(h.Errors) == 0
+}
+
+// addError records a problem found in header
+func (h *Header) addError(format string, args ...interface{}) {
+	h.Errors = append(h.Errors, fmt.Sprintf(format, args...))
+}
+
+// parseNES parses an iNES or NES 2.0 header
+func parseNES(data []byte) *Header {
+	result := &Header{System: SystemNES, Format: "iNES"}
+
+	if len(data) < 16 {
+		result.addError("truncated header")
+		return result
+	}
+
+	prg := int(data[4]) * 0x4000
+	chr := int(data[5]) * 0x2000
+
+	if data[7]&0x0C == 0x08 {
+		result.Format = "NES 2.0"
+
+		// size MSB nibbles
+		prg += int(data[9]&0x0F) << 8 * 0x4000
+		chr += int(data[9]>>4) << 8 * 0x2000
+
+		switch data[12] & 0x03 {
+		case 0:
+			result.Region = "USA"
+		case 1:
+			result.Region = "Europe"
+		}
+	} else if data[9]&0x01 != 0 {
+		result.Region = "Europe"
+	}
+
+	size := 16 + prg + chr
+	if data[6]&0x04 != 0 {
+		// trainer
+		size += 512
+	}
+
+	if prg == 0 {
+		result.addError("no PRG ROM")
+	} else if len(data) < size {
+		result.addError("truncated rom: %d bytes but header declares %d", len(data), size)
+	}
+
+	return result
+}
+
+// snesRegions holds the regions of SNES country codes
+var snesRegions = map[byte]string{
+	0x00: "Japan",
+	0x01: "USA",
+	0x02: "Europe",
+	0x03: "Sweden",
+	0x04: "Finland",
+	0x05: "Denmark",
+	0x06: "France",
+	0x07: "Netherlands",
+	0x08: "Spain",
+	0x09: "Germany",
+	0x0A: "Italy",
+	0x0B: "China",
+	0x0D: "Korea",
+	0x0F: "Canada",
+	0x10: "Brazil",
+	0x11: "Australia",
+}
+
+// parseSNES parses a SNES internal header at given offset, with given copier header size
+func parseSNES(data []byte, offset int, copier int) *Header {
+	result := &Header{System: SystemSNES, Format: "LoROM"}
+
+	if offset-copier == 0xFFC0 {
+		result.Format = "HiROM"
+	}
+
+	result.Title = text(data[offset : offset+21])
+	result.Region = snesRegions[data[offset+0x19]]
+
+	checksum := le16(data[offset+0x1E:])
+	if sum := snesChecksum(data[copier:]); sum != checksum {
+		result.addError("bad checksum: 0x%04X (expected 0x%04X)", checksum, sum)
+	}
+
+	return result
+}
+
+// snesChecksum computes the checksum of given SNES rom data, without copier header. Roms which size is not a power of two are mirrored up to the next power of two.
+func snesChecksum(data []byte) uint16 {
+	size := 1
+	for size*2 <= len(data) {
+		size *= 2
+	}
+
+	sum := sumBytes(data[:size])
+
+	if rest := data[size:]; len(rest) > 0 {
+		// mirror remaining part
+		mirrored := 0
+		for mirrored < size {
+			sum += sumBytes(rest)
+			mirrored += len(rest)
+		}
+	}
+
+	return uint16(sum)
+}
+
+// parseGB parses a Game Boy or Game Boy Color header
+func parseGB(data []byte) *Header {
+	result := &Header{System: SystemGB, Format: "DMG"}
+
+	if len(data) < 0x150 {
+		result.addError("truncated header")
+		return result
+	}
+
+	titleEnd := 0x144
+	if data[0x143]&0x80 != 0 {
+		result.System = SystemGBC
+		result.Format = "CGB"
+		titleEnd = 0x143
+	}
+
+	result.Title = text(data[0x134:titleEnd])
+
+	if data[0x14A] == 0x00 {
+		result.Region = "Japan"
+	}
+
+	var x byte
+	for _, b := range data[0x134:0x14D] {
+		x = x - b - 1
+	}
+
+	if x != data[0x14D] {
+		result.addError("bad header checksum: 0x%02X (expected 0x%02X)", data[0x14D], x)
+	}
+
+	global := uint16(data[0x14E])<<8 | uint16(data[0x14F])
+	if sum := uint16(sumBytes(data) - uint(data[0x14E]) - uint(data[0x14F])); sum != global {
+		result.addError("bad global checksum: 0x%04X (expected 0x%04X)", global, sum)
+	}
+
+	return result
+}
+
+// gbaRegions holds the regions of GBA game code last character
+var gbaRegions = map[byte]string{
+	'J': "Japan",
+	'E': "USA",
+	'P': "Europe",
+	'D': "Germany",
+	'F': "France",
+	'I': "Italy",
+	'S': "Spain",
+	'K': "Korea",
+	'C': "China",
+}
+
+// parseGBA parses a Game Boy Advance header
+func parseGBA(data []byte) *Header {
+	result := &Header{System: SystemGBA, Format: "AGB"}
+
+	if len(data) < 0xC0 {
+		result.addError("truncated header")
+		return result
+	}
+
+	result.Title = text(data[0xA0:0xAC])
+	result.Region = gbaRegions[data[0xAF]]
+
+	var sum byte
+	for _, b := range data[0xA0:0xBD] {
+		sum -= b
+	}
+	sum -= 0x19
+
+	if sum != data[0xBD] {
+		result.addError("bad header checksum: 0x%02X (expected 0x%02X)", data[0xBD], sum)
+	}
+
+	return result
+}
+
+// megaDriveRegions holds the regions of old style Mega Drive region codes
+var megaDriveRegions = map[string]string{
+	"J": "Japan",
+	"U": "USA",
+	"E": "Europe",
+}
+
+// parseMegaDrive parses a Mega Drive or 32X header
+func parseMegaDrive(data []byte) *Header {
+	result := &Header{System: SystemMegaDrive, Format: "MD"}
+
+	if hasAt(data, 0x100, []byte("SEGA 32X")) {
+		result.System = System32X
+		result.Format = "32X"
+	}
+
+	if len(data) < 0x200 {
+		result.addError("truncated header")
+		return result
+	}
+
+	result.Title = text(data[0x150:0x180])
+	if result.Title == "" {
+		result.Title = text(data[0x120:0x150])
+	}
+
+	result.Region = megaDriveRegions[text(data[0x1F0:0x1F3])]
+
+	checksum := uint16(data[0x18E])<<8 | uint16(data[0x18F])
+
+	var sum uint16
+	for i := 0x200; i+1 < len(data); i += 2 {
+		sum += uint16(data[i])<<8 | uint16(data[i+1])
+	}
+
+	if sum != checksum {
+		result.addError("bad checksum: 0x%04X (expected 0x%04X)", checksum, sum)
+	}
+
+	return result
+}
+
+// n64Regions holds the regions of N64 country codes
+var n64Regions = map[byte]string{
+	'J': "Japan",
+	'E': "USA",
+	'P': "Europe",
+	'X': "Europe",
+	'Y': "Europe",
+	'D': "Germany",
+	'F': "France",
+	'I': "Italy",
+	'S': "Spain",
+	'U': "Australia",
+	'C': "China",
+	'K': "Korea",
+}
+
+// parseN64 parses a N64 header, in any byte order
+func parseN64(data []byte) *Header {
+	result := &Header{System: SystemN64, Format: N64ByteOrder(data)}
+
+	if len(data) < 0x40 {
+		result.addError("truncated header")
+		return result
+	}
+
+	header := ToBigEndian(data[:0x40], result.Format)
+
+	result.Title = text(header[0x20:0x34])
+	result.Region = n64Regions[header[0x3E]]
+
+	return result
+}
+
+// N64ByteOrder returns the byte order of given N64 rom data, or an empty string if unknown
+func N64ByteOrder(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, n64Magics[0]):
+		return N64BigEndian
+	case bytes.HasPrefix(data, n64Magics[1]):
+		return N64ByteSwapped
+	case bytes.HasPrefix(data, n64Magics[2]):
+		return N64LittleEndian
+	}
+
+	return ""
+}
+
+// ToBigEndian returns a copy of given N64 rom data, converted from given byte order to big endian
+func ToBigEndian(data []byte, order string) []byte {
+	result := make([]byte, len(data))
+	copy(result, data)
+
+	switch order {
+	case N64ByteSwapped:
+		for i := 0; i+1 < len(result); i += 2 {
+			result[i], result[i+1] = result[i+1], result[i]
+		}
+	case N64LittleEndian:
+		for i := 0; i+3 < len(result); i += 4 {
+			result[i], result[i+1], result[i+2], result[i+3] = result[i+3], result[i+2], result[i+1], result[i]
+		}
+	}
+
+	return result
+}
+
+// ConvertByteOrder returns a copy of given N64 rom data, converted from a byte order to another
+func ConvertByteOrder(data []byte, from string, to string) []byte {
+	// swapping bytes back and forth is the same operation
+	return ToBigEndian(ToBigEndian(data, from), to)
+}
+
+// text returns given header text, without padding
+func text(data []byte) string {
+	return strings.TrimSpace(strings.Trim(string(data), "\x00\xff"))
+}
+
+// sumBytes returns the sum of given bytes
+func sumBytes(data []byte) uint {
+	var result uint
+	for _, b := range data {
+		result += uint(b)
+	}
+
+	return result
+}
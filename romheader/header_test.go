@@ -0,0 +1,181 @@
+package romheader
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// gbRom returns a Game Boy rom with valid checksums
+func gbRom(title string, cgb byte, destination byte) []byte {
+	data := romWith(0x8000, 0x104, gbLogo)
+	copy(data[0x134:], title)
+	data[0x143] = cgb
+	data[0x14A] = destination
+
+	var x byte
+	for _, b := range data[0x134:0x14D] {
+		x = x - b - 1
+	}
+	data[0x14D] = x
+
+	sum := uint16(sumBytes(data))
+	data[0x14E] = byte(sum >> 8)
+	data[0x14F] = byte(sum)
+
+	return data
+}
+
+// gbaRom returns a Game Boy Advance rom with a valid header checksum
+func gbaRom(title string, code string) []byte {
+	data := romWith(0x1000, 0x04, gbaLogo)
+	copy(data[0xA0:], title)
+	copy(data[0xAC:], code)
+
+	var sum byte
+	for _, b := range data[0xA0:0xBD] {
+		sum -= b
+	}
+	data[0xBD] = sum - 0x19
+
+	return data
+}
+
+// megaDriveRom returns a Mega Drive rom with a valid checksum
+func megaDriveRom(title string, region string) []byte {
+	data := romWith(0x1000, 0x100, []byte("SEGA MEGA DRIVE "))
+	copy(data[0x150:], title)
+	copy(data[0x1F0:], region)
+
+	for i := 0x200; i < len(data); i++ {
+		data[i] = byte(i)
+	}
+
+	var sum uint16
+	for i := 0x200; i < len(data); i += 2 {
+		sum += uint16(data[i])<<8 | uint16(data[i+1])
+	}
+	data[0x18E] = byte(sum >> 8)
+	data[0x18F] = byte(sum)
+
+	return data
+}
+
+// snesRom returns a LoROM SNES rom with a valid checksum
+func snesRom(title string, country byte) []byte {
+	data := make([]byte, 0x20000)
+	copy(data[0x7FC0:], title)
+	data[0x7FD9] = country
+
+	// checksum and complement bytes sum to 0x1FE
+	data[0x7FDC], data[0x7FDD], data[0x7FDE], data[0x7FDF] = 0xFF, 0xFF, 0x00, 0x00
+
+	sum := uint16(sumBytes(data))
+	data[0x7FDC], data[0x7FDD] = byte(^sum), byte(^sum>>8)
+	data[0x7FDE], data[0x7FDF] = byte(sum), byte(sum>>8)
+
+	return data
+}
+
+// n64Rom returns a N64 rom header in given byte order
+func n64Rom(title string, country byte, order string) []byte {
+	data := romWith(0x1000, 0, n64Magics[0])
+	copy(data[0x20:], title)
+	data[0x3E] = country
+
+	// conversion to big endian is its own inverse
+	return ToBigEndian(data, order)
+}
+
+func TestParse(t *testing.T) {
+	badGB := gbRom("TETRIS", 0x00, 0x01)
+	badGB[0x200] = 0x42
+
+	badMD := megaDriveRom("SONIC THE HEDGEHOG", "JUE")
+	badMD[0x300]++
+
+	nes2 := romWith(16+0x8000+0x2000, 0, []byte("NES\x1a"))
+	nes2[4], nes2[5], nes2[7], nes2[12] = 2, 1, 0x08, 0x01
+
+	tests := []struct {
+		name   string
+		data   []byte
+		system string
+		format string
+		title  string
+		region string
+		valid  bool
+	}{
+		{"gb", gbRom("TETRIS", 0x00, 0x00), SystemGB, "DMG", "TETRIS", "Japan", true},
+		{"gbc", gbRom("POKEMON_SLV", 0xC0, 0x01), SystemGBC, "CGB", "POKEMON_SLV", "", true},
+		{"gb bad global checksum", badGB, SystemGB, "DMG", "TETRIS", "", false},
+		{"gba", gbaRom("METROID4", "AMTP"), SystemGBA, "AGB", "METROID4", "Europe", true},
+		{"megadrive", megaDriveRom("SONIC THE HEDGEHOG", "U"), SystemMegaDrive, "MD", "SONIC THE HEDGEHOG", "USA", true},
+		{"megadrive bad checksum", badMD, SystemMegaDrive, "MD", "SONIC THE HEDGEHOG", "", false},
+		{"snes", snesRom("SUPER MARIOWORLD", 0x01), SystemSNES, "LoROM", "SUPER MARIOWORLD", "USA", true},
+		{"snes copier", append(make([]byte, 0x200), snesRom("F-ZERO", 0x00)...), SystemSNES, "LoROM", "F-ZERO", "Japan", true},
+		{"nes", romWith(16+0x8000+0x2000, 0, []byte("NES\x1a\x02\x01")), SystemNES, "iNES", "", "", true},
+		{"nes 2.0", nes2, SystemNES, "NES 2.0", "", "Europe", true},
+		{"nes truncated", romWith(16+0x4000, 0, []byte("NES\x1a\x02\x01")), SystemNES, "iNES", "", "", false},
+		{"n64 z64", n64Rom("SUPER MARIO 64", 'E', N64BigEndian), SystemN64, N64BigEndian, "SUPER MARIO 64", "USA", true},
+		{"n64 v64", n64Rom("SUPER MARIO 64", 'J', N64ByteSwapped), SystemN64, N64ByteSwapped, "SUPER MARIO 64", "Japan", true},
+		{"n64 n64", n64Rom("SUPER MARIO 64", 'P', N64LittleEndian), SystemN64, N64LittleEndian, "SUPER MARIO 64", "Europe", true},
+	}
+
+	for _, test := range tests {
+		h := Parse(test.data)
+		if h == nil {
+			t.Errorf("Header parsing failed for %s, no header found", test.name)
+			continue
+		}
+
+		if (h.System != test.system) || (h.Format != test.format) || (h.Title != test.title) {
+			t.Errorf("Header parsing failed for %s, got '%v'", test.name, h)
+		}
+
+		if (test.valid || (test.region != "")) && (h.Region != test.region) {
+			t.Errorf("Header region failed for %s, got '%s' but expected '%s'", test.name, h.Region, test.region)
+		}
+
+		if h.Valid() != test.valid {
+			t.Errorf("Header validation failed for %s, got errors: %s", test.name, strings.Join(h.Errors, ", "))
+		}
+	}
+
+	if h := Parse(make([]byte, 0x1000)); h != nil {
+		t.Errorf("Header parsing of blank data failed, got '%v'", h)
+	}
+}
+
+func TestToBigEndian(t *testing.T) {
+	for _, order := range []string{N64BigEndian, N64ByteSwapped, N64LittleEndian} {
+		data := n64Rom("ZELDA", 'E', order)
+
+		if result := N64ByteOrder(data); result != order {
+			t.Errorf("Byte order detection failed, got '%s' but expected '%s'", result, order)
+		}
+
+		if result := ToBigEndian(data, order); N64ByteOrder(result) != N64BigEndian {
+			t.Errorf("Byte order conversion failed from '%s'", order)
+		}
+	}
+}
+
+func TestConvertByteOrder(t *testing.T) {
+	orders := []string{N64BigEndian, N64ByteSwapped, N64LittleEndian}
+
+	for _, from := range orders {
+		for _, to := range orders {
+			data := n64Rom("ZELDA", 'E', from)
+			result := ConvertByteOrder(data, from, to)
+
+			if N64ByteOrder(result) != to {
+				t.Errorf("Byte order conversion failed from '%s' to '%s', got '%s'", from, to, N64ByteOrder(result))
+			}
+
+			if !bytes.Equal(result, n64Rom("ZELDA", 'E', to)) {
+				t.Errorf("Byte order conversion failed from '%s' to '%s', data mismatch", from, to)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package romheader
+
+import "bytes"
+
+// systems detected from rom headers, as "<Manufacturer> - <Name>"
+const (
+	SystemNES       = "Nintendo - Nintendo Entertainment System"
+	SystemSNES      = "Nintendo - Super Nintendo Entertainment System"
+	SystemGB        = "Nintendo - Game Boy"
+	SystemGBC       = "Nintendo - Game Boy Color"
+	SystemGBA       = "Nintendo - Game Boy Advance"
+	SystemN64       = "Nintendo - Nintendo 64"
+	SystemMegaDrive = "Sega - Mega Drive - Genesis"
+	System32X       = "Sega - 32X"
+	SystemSMS       = "Sega - Master System - Mark III"
+	SystemGameGear  = "Sega - Game Gear"
+	SystemLynx      = "Atari - Lynx"
+	SystemAtari7800 = "Atari - 7800"
+)
+
+// MinSize is the number of bytes needed to detect all supported headers
+const MinSize = 0x10000 + 0x200
+
+var (
+	// first bytes of the Nintendo logo in Game Boy header
+	gbLogo = []byte{0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B}
+
+	// first bytes of the Nintendo logo in Game Boy Advance header
+	gbaLogo = []byte{0x24, 0xFF, 0xAE, 0x51, 0x69, 0x9A, 0xA2, 0x21}
+
+	// N64 first word, in big endian, byte swapped and little endian orders
+	n64Magics = [][]byte{
+		{0x80, 0x37, 0x12, 0x40},
+		{0x37, 0x80, 0x40, 0x12},
+		{0x40, 0x12, 0x37, 0x80},
+	}
+)
+
+// Detect returns the system of given rom data, found from header signatures, or an empty string if unknown
+func Detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("NES\x1a")):
+		return SystemNES
+
+	case bytes.HasPrefix(data, []byte("LYNX")):
+		return SystemLynx
+
+	case hasAt(data, 1, []byte("ATARI7800")):
+		return SystemAtari7800
+
+	case isN64(data):
+		return SystemN64
+
+	case hasAt(data, 0x104, gbLogo):
+		// CGB flag: 0x80 for dual mode, 0xC0 for Game Boy Color only
+		if (len(data) > 0x143) && (data[0x143]&0x80 != 0) {
+			return SystemGBC
+		}
+
+		return SystemGB
+
+	case hasAt(data, 0x04, gbaLogo):
+		return SystemGBA
+
+	case hasAt(data, 0x100, []byte("SEGA 32X")):
+		return System32X
+
+	case hasAt(data, 0x100, []byte("SEGA")):
+		return SystemMegaDrive
+	}
+
+	if system := detectSega8(data); system != "" {
+		return system
+	}
+
+	if snesHeaderOffset(data) >= 0 {
+		return SystemSNES
+	}
+
+	return ""
+}
+
+// isN64 returns true if given data starts with a N64 header, in any byte order
+func isN64(data []byte) bool {
+	for _, magic := range n64Magics {
+		if bytes.HasPrefix(data, magic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectSega8 returns the system of given Master System or Game Gear rom, or an empty string if the "TMR SEGA" header is not found
+func detectSega8(data []byte) string {
+	for _, offset := range []int{0x7FF0, 0x3FF0, 0x1FF0} {
+		if !hasAt(data, offset, []byte("TMR SEGA")) {
+			continue
+		}
+
+		// region code is the high nibble of last header byte
+		switch data[offset+0xF] >> 4 {
+		case 5, 6, 7:
+			return SystemGameGear
+		default:
+			return SystemSMS
+		}
+	}
+
+	return ""
+}
+
+// snesHeaderOffset returns the offset of a valid SNES internal header in given data, or -1 if not found. A valid header has a checksum and its complement that sum to 0xFFFF.
+func snesHeaderOffset(data []byte) int {
+	offset, _ := snesHeader(data)
+	return offset
+}
+
+// snesHeader returns the offset of a valid SNES internal header in given data and the size of copier header, or -1 if not found
+func snesHeader(data []byte) (int, int) {
+	for _, base := range []int{0x7FC0, 0xFFC0} {
+		for _, copier := range []int{0, 0x200} {
+			offset := base + copier
+			if len(data) < offset+0x20 {
+				continue
+			}
+
+			complement := le16(data[offset+0x1C:])
+			checksum := le16(data[offset+0x1E:])
+
+			if (complement^checksum == 0xFFFF) && (checksum != 0) && (complement != 0) {
+				return offset, copier
+			}
+		}
+	}
+
+	return -1, 0
+}
+
+// hasAt returns true if given data contains given bytes at given offset
+func hasAt(data []byte, offset int, b []byte) bool {
+	return (len(data) >= offset+len(b)) && bytes.Equal(data[offset:offset+len(b)], b)
+}
+
+// le16 returns the little endian uint16 at start of given data
+func le16(data []byte) uint16 {
+	return uint16(data[0]) | uint16(data[1])<<8
+}
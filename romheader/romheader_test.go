@@ -0,0 +1,51 @@
+package romheader
+
+import "testing"
+
+// romWith returns a blank rom of given size, with given bytes at given offset
+func romWith(size int, offset int, b []byte) []byte {
+	result := make([]byte, size)
+	copy(result[offset:], b)
+	return result
+}
+
+func TestDetect(t *testing.T) {
+	gbc := romWith(0x8000, 0x104, gbLogo)
+	gbc[0x143] = 0xC0
+
+	gg := romWith(0x8000, 0x7FF0, []byte("TMR SEGA"))
+	gg[0x7FFF] = 0x7C
+
+	sms := romWith(0x8000, 0x7FF0, []byte("TMR SEGA"))
+	sms[0x7FFF] = 0x4C
+
+	snes := romWith(0x10000, 0x7FDC, []byte{0x34, 0x12, 0xCB, 0xED})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"nes", romWith(0x4010, 0, []byte("NES\x1a")), SystemNES},
+		{"gb", romWith(0x8000, 0x104, gbLogo), SystemGB},
+		{"gbc", gbc, SystemGBC},
+		{"gba", romWith(0x1000, 0x04, gbaLogo), SystemGBA},
+		{"n64 z64", romWith(0x1000, 0, n64Magics[0]), SystemN64},
+		{"n64 v64", romWith(0x1000, 0, n64Magics[1]), SystemN64},
+		{"megadrive", romWith(0x1000, 0x100, []byte("SEGA MEGA DRIVE ")), SystemMegaDrive},
+		{"32x", romWith(0x1000, 0x100, []byte("SEGA 32X")), System32X},
+		{"gamegear", gg, SystemGameGear},
+		{"mastersystem", sms, SystemSMS},
+		{"snes", snes, SystemSNES},
+		{"lynx", romWith(0x1000, 0, []byte("LYNX")), SystemLynx},
+		{"7800", romWith(0x1000, 1, []byte("ATARI7800")), SystemAtari7800},
+		{"unknown", make([]byte, 0x1000), ""},
+		{"short", []byte{0x01}, ""},
+	}
+
+	for _, test := range tests {
+		if result := Detect(test.data); result != test.expected {
+			t.Errorf("Header detection failed for %s, got '%s' but expected '%s'", test.name, result, test.expected)
+		}
+	}
+}
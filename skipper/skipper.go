@@ -0,0 +1,324 @@
+package skipper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Detector represents a ClrMamePro header skipper definition, as published by no-intro, eg: "No-Intro_NES.xml"
+type Detector struct {
+	XMLName xml.Name `xml:"detector"`
+	Name    string   `xml:"name"`
+	Author  string   `xml:"author,omitempty"`
+	Version string   `xml:"version,omitempty"`
+	Rules   []*Rule  `xml:"rule"`
+
+	// file path
+	File string `xml:"-"`
+}
+
+// Rule represents a header skipper rule: when all tests pass, rom data is found between start and end offsets
+type Rule struct {
+	StartOffset string  `xml:"start_offset,attr"`
+	EndOffset   string  `xml:"end_offset,attr"`
+	Operation   string  `xml:"operation,attr"`
+	Tests       []*Test `xml:",any"`
+
+	// parsed offsets, end is -1 for end of file
+	start int64
+	end   int64
+}
+
+// Test represents a rule test, its kind is the XML element name: "data", "or", "xor", "and" or "file"
+type Test struct {
+	XMLName  xml.Name
+	Offset   string `xml:"offset,attr"`
+	Value    string `xml:"value,attr"`
+	Mask     string `xml:"mask,attr"`
+	Result   string `xml:"result,attr"`
+	Size     string `xml:"size,attr"`
+	Operator string `xml:"operator,attr"`
+}
+
+// Load reads header skipper definition file at given path
+func Load(filePath string) (*Detector, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	result.File = filePath
+
+	return result, nil
+}
+
+// Parse parses given header skipper definition
+func Parse(data []byte) (*Detector, error) {
+	result := &Detector{}
+	if err := xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range result.Rules {
+		if err := rule.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// LoadAll reads header skipper definitions at given paths, a path can be either a XML file or a directory containing XML files
+func LoadAll(paths []string) ([]*Detector, error) {
+	result := []*Detector{}
+
+	for _, p := range paths {
+		fileInfo, err := os.Stat(p)
+		if err != nil {
+			return result, err
+		}
+
+		files := []string{p}
+
+		if fileInfo.IsDir() {
+			infos, err := ioutil.ReadDir(p)
+			if err != nil {
+				return result, err
+			}
+
+			files = []string{}
+			for _, info := range infos {
+				if !info.IsDir() && (strings.ToLower(filepath.Ext(info.Name())) == ".xml") {
+					files = append(files, path.Join(p, info.Name()))
+				}
+			}
+		}
+
+		for _, file := range files {
+			d, err := Load(file)
+			if err != nil {
+				return result, err
+			}
+
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
+// Key returns the lowercased definition file name without extension and without the "No-Intro_" prefix, eg: "nes" for "No-Intro_NES.xml"
+func (d *Detector) Key() string {
+	name := path.Base(d.File)
+	if d.File == "" {
+		name = d.Name
+	}
+
+	name = strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+
+	return strings.TrimPrefix(name, "no-intro_")
+}
+
+// Is returns true if given definition file name, as referenced in DAT headers, designates that detector
+func (d *Detector) Is(fileName string) bool {
+	return strings.EqualFold(fileName, path.Base(d.File)) || strings.EqualFold(fileName, d.Name)
+}
+
+// Match returns the first rule that matches given rom data, or nil if none
+func (d *Detector) Match(data []byte) *Rule {
+	if d == nil {
+		return nil
+	}
+
+	for _, rule := range d.Rules {
+		if rule.Match(data) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// Split returns the header and the headerless data of given rom data. The header is nil when no rule matches, and data is returned as is.
+func (d *Detector) Split(data []byte) ([]byte, []byte) {
+	rule := d.Match(data)
+	if rule == nil {
+		return nil, data
+	}
+
+	end := int64(len(data))
+	if (rule.end >= 0) && (rule.end < end) {
+		end = rule.end
+	}
+
+	return data[:rule.start], apply(rule.Operation, data[rule.start:end])
+}
+
+// parse checks rule offsets and operation
+func (r *Rule) parse() error {
+	var err error
+
+	if r.start, err = parseOffset(r.StartOffset, 0); err != nil {
+		return err
+	}
+
+	if r.end, err = parseOffset(r.EndOffset, -1); err != nil {
+		return err
+	}
+
+	switch r.Operation {
+	case "", "none", "bitswap", "byteswap", "wordswap", "wordbyteswap":
+	default:
+		return fmt.Errorf("Unsupported rule operation: %s", r.Operation)
+	}
+
+	for _, t := range r.Tests {
+		switch t.XMLName.Local {
+		case "data", "or", "xor", "and", "file":
+		default:
+			return fmt.Errorf("Unsupported rule test: %s", t.XMLName.Local)
+		}
+	}
+
+	return nil
+}
+
+// Match returns true if all rule tests pass on given rom data
+func (r *Rule) Match(data []byte) bool {
+	if int64(len(data)) <= r.start {
+		return false
+	}
+
+	for _, t := range r.Tests {
+		if t.Match(data) != (t.Result != "false") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Match returns true if test condition is verified on given rom data, regardless of expected result
+func (t *Test) Match(data []byte) bool {
+	if t.XMLName.Local == "file" {
+		return t.matchFile(int64(len(data)))
+	}
+
+	offset, err := parseOffset(t.Offset, 0)
+	if err != nil {
+		return false
+	}
+
+	value, err := hex.DecodeString(t.Value)
+	if err != nil {
+		return false
+	}
+
+	if (offset < 0) || (offset+int64(len(value)) > int64(len(data))) {
+		return false
+	}
+
+	found := data[offset : offset+int64(len(value))]
+
+	if t.XMLName.Local == "data" {
+		return bytes.Equal(found, value)
+	}
+
+	mask, err := hex.DecodeString(t.Mask)
+	if (err != nil) || (len(mask) != len(value)) {
+		return false
+	}
+
+	masked := make([]byte, len(found))
+	for i := range found {
+		switch t.XMLName.Local {
+		case "or":
+			masked[i] = found[i] | mask[i]
+		case "xor":
+			masked[i] = found[i] ^ mask[i]
+		case "and":
+			masked[i] = found[i] & mask[i]
+		}
+	}
+
+	return bytes.Equal(masked, value)
+}
+
+// matchFile checks given file size against test size, which can be "PO2" for a power of two
+func (t *Test) matchFile(size int64) bool {
+	if strings.EqualFold(t.Size, "PO2") {
+		return (size > 0) && (size&(size-1) == 0)
+	}
+
+	expected, err := parseOffset(t.Size, 0)
+	if err != nil {
+		return false
+	}
+
+	switch t.Operator {
+	case "less":
+		return size < expected
+	case "greater":
+		return size > expected
+	}
+
+	return size == expected
+}
+
+// parseOffset parses an hexadecimal offset, "EOF" or an empty string returns given default value
+func parseOffset(str string, def int64) (int64, error) {
+	if (str == "") || strings.EqualFold(str, "EOF") {
+		return def, nil
+	}
+
+	result, err := strconv.ParseInt(str, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid offset: %s", str)
+	}
+
+	return result, nil
+}
+
+// apply returns a copy of given data, transformed by given rule operation
+func apply(operation string, data []byte) []byte {
+	result := make([]byte, len(data))
+	copy(result, data)
+
+	switch operation {
+	case "bitswap":
+		for i, b := range result {
+			var r byte
+			for j := uint(0); j < 8; j++ {
+				r |= ((b >> j) & 1) << (7 - j)
+			}
+			result[i] = r
+		}
+	case "byteswap":
+		for i := 0; i+1 < len(result); i += 2 {
+			result[i], result[i+1] = result[i+1], result[i]
+		}
+	case "wordswap":
+		for i := 0; i+3 < len(result); i += 4 {
+			result[i], result[i+1], result[i+2], result[i+3] = result[i+3], result[i+2], result[i+1], result[i]
+		}
+	case "wordbyteswap":
+		for i := 0; i+3 < len(result); i += 4 {
+			result[i], result[i+1], result[i+2], result[i+3] = result[i+2], result[i+3], result[i], result[i+1]
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+package skipper
+
+import (
+	"bytes"
+	"testing"
+)
+
+const nesDetector = `<?xml version="1.0"?>
+<detector>
+	<name>No-Intro_NES.xml</name>
+	<author>No-Intro</author>
+	<version>1.0</version>
+	<rule start_offset="10" end_offset="EOF" operation="none">
+		<data offset="0" value="4E45531A" result="true"/>
+	</rule>
+</detector>`
+
+const snesDetector = `<?xml version="1.0"?>
+<detector>
+	<name>SNES copier headers</name>
+	<rule start_offset="200">
+		<file size="PO2" result="false"/>
+		<and offset="0" mask="FF" value="00" result="false"/>
+	</rule>
+</detector>`
+
+const n64Detector = `<?xml version="1.0"?>
+<detector>
+	<name>N64 byte order</name>
+	<rule start_offset="0" operation="byteswap">
+		<data offset="0" value="37804012"/>
+	</rule>
+	<rule start_offset="0" operation="wordswap">
+		<data offset="0" value="40123780"/>
+	</rule>
+</detector>`
+
+func TestParse(t *testing.T) {
+	d, err := Parse([]byte(nesDetector))
+	if err != nil {
+		t.Fatalf("Failed to parse detector: %v", err)
+	}
+
+	if (d.Name != "No-Intro_NES.xml") || (len(d.Rules) != 1) || (len(d.Rules[0].Tests) != 1) {
+		t.Errorf("Detector parsing failed, got: %+v", d)
+	}
+
+	if d.Key() != "nes" {
+		t.Errorf("Detector key failed, got '%s'", d.Key())
+	}
+
+	if !d.Is("no-intro_nes.xml") {
+		t.Errorf("Detector file name matching failed")
+	}
+
+	invalid := []string{
+		`<detector><rule start_offset="zz"/></detector>`,
+		`<detector><rule start_offset="0" operation="shuffle"/></detector>`,
+		`<detector><rule start_offset="0"><crc value="00"/></rule></detector>`,
+		`<detector>`,
+	}
+
+	for _, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Detector parsing should have failed for: %s", data)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	nes, _ := Parse([]byte(nesDetector))
+	snes, _ := Parse([]byte(snesDetector))
+	n64, _ := Parse([]byte(n64Detector))
+
+	rom := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	headered := append([]byte("NES\x1a"), make([]byte, 12)...)
+	headered = append(headered, rom...)
+
+	copier := append(make([]byte, 0x200), bytes.Repeat([]byte{0xAA}, 0x400)...)
+	copier[0] = 0x01
+
+	tests := []struct {
+		name   string
+		d      *Detector
+		data   []byte
+		header int
+		result []byte
+	}{
+		{"nes headered", nes, headered, 16, rom},
+		{"nes headerless", nes, rom, 0, rom},
+		{"snes copier", snes, copier, 0x200, bytes.Repeat([]byte{0xAA}, 0x400)},
+		{"snes no copier", snes, copier[0x200:], 0, copier[0x200:]},
+		{"n64 byte swapped", n64, []byte{0x37, 0x80, 0x40, 0x12, 1, 2}, 0, []byte{0x80, 0x37, 0x12, 0x40, 2, 1}},
+		{"n64 little endian", n64, []byte{0x40, 0x12, 0x37, 0x80}, 0, []byte{0x80, 0x37, 0x12, 0x40}},
+		{"nil detector", nil, headered, 0, headered},
+	}
+
+	for _, test := range tests {
+		header, result := test.d.Split(test.data)
+
+		if len(header) != test.header {
+			t.Errorf("Header split failed for %s, got a %d bytes header but expected %d", test.name, len(header), test.header)
+		}
+
+		if !bytes.Equal(result, test.result) {
+			t.Errorf("Header split failed for %s, got %d bytes of unexpected data", test.name, len(result))
+		}
+	}
+}
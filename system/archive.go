@@ -6,12 +6,44 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/aymerick/charette/core"
 	"github.com/aymerick/charette/helpers"
 	"github.com/aymerick/charette/rom"
 )
 
+// romExtensions holds the systems of rom file extensions, indexed by extension. Loose roms of extracted directories are recognized by these extensions.
+var romExtensions = map[string]string{
+	".a78": "Atari - 7800",
+	".col": "Coleco - ColecoVision",
+	".fds": "Nintendo - Famicom Disk System",
+	".gb":  "Nintendo - Game Boy",
+	".gba": "Nintendo - Game Boy Advance",
+	".gbc": "Nintendo - Game Boy Color",
+	".gen": "Sega - Mega Drive - Genesis",
+	".gg":  "Sega - Game Gear",
+	".lnx": "Atari - Lynx",
+	".md":  "Sega - Mega Drive - Genesis",
+	".min": "Nintendo - Pokemon Mini",
+	".n64": "Nintendo - Nintendo 64",
+	".nes": "Nintendo - Nintendo Entertainment System",
+	".ngc": "SNK - Neo Geo Pocket Color",
+	".ngp": "SNK - Neo Geo Pocket",
+	".pce": "NEC - PC Engine - TurboGrafx 16",
+	".sfc": "Nintendo - Super Nintendo Entertainment System",
+	".sg":  "Sega - SG-1000",
+	".smc": "Nintendo - Super Nintendo Entertainment System",
+	".sms": "Sega - Master System - Mark III",
+	".v64": "Nintendo - Nintendo 64",
+	".vb":  "Nintendo - Virtual Boy",
+	".vec": "GCE - Vectrex",
+	".ws":  "Bandai - WonderSwan",
+	".wsc": "Bandai - WonderSwan Color",
+	".z64": "Nintendo - Nintendo 64",
+	".32x": "Sega - 32X",
+}
+
 // Archive represents an archive of system roms
 type Archive struct {
 	// gaming system
@@ -20,16 +52,13 @@ type Archive struct {
 	// archive path
 	Path string
 
-	// output directory path
-	Output string
-
 	// options
 	Options *core.Options
 
 	// working directory path
 	WorkingDir string
 
-	// selected games
+	// candidate games, indexed by normalized name
 	Games map[string]*rom.Game
 
 	// processed files number
@@ -38,22 +67,20 @@ type Archive struct {
 	// skipped files number
 	Skipped int
 
-	// selected regions stats
-	RegionsStats map[string]int
+	// roms number
+	Seen int
 }
 
 // NewArchive instanciates a new Archive
-func NewArchive(s *System, filePath string, output string, options *core.Options) *Archive {
+func NewArchive(s *System, filePath string, options *core.Options) *Archive {
 	result := &Archive{
-		System:       s,
-		Path:         filePath,
-		Output:       output,
-		Options:      options,
-		Games:        map[string]*rom.Game{},
-		RegionsStats: map[string]int{},
+		System:  s,
+		Path:    filePath,
+		Options: options,
+		Games:   map[string]*rom.Game{},
 	}
 
-	result.WorkingDir = path.Join(options.Tmp, helpers.FileBase(filePath))
+	result.WorkingDir = path.Join(options.Tmp, path.Base(filePath))
 
 	return result
 }
@@ -62,24 +89,33 @@ func (a *Archive) log(msg string) {
 	fmt.Printf("[%s] %s", a.System.Infos.Name, msg)
 }
 
-// Process filters roms in archive
+// IsDir returns true if that archive is an already extracted directory
+func (a *Archive) IsDir() bool {
+	return helpers.IsDir(a.Path)
+}
+
+// Process extracts archive and collects candidate roms, that are kept in working directory until Cleanup() is called. An already extracted directory is processed in place.
 func (a *Archive) Process() error {
+	if a.IsDir() {
+		if a.Options.Debug {
+			a.log(fmt.Sprintf("Processing directory in place: %s\n", a.Path))
+		}
+
+		return a.processDir(a.Path)
+	}
+
 	// extract archive
 	if err := a.extract(); err != nil {
 		return err
 	}
 
 	// process roms
-	if err := a.selectRoms(); err != nil {
-		return err
-	}
-
-	// delete extracted archive directory
-	if err := a.cleanup(); err != nil {
-		return err
-	}
+	return a.processDir(a.WorkingDir)
+}
 
-	return nil
+// Cleanup deletes all extracted files. Files of an already extracted directory are never deleted.
+func (a *Archive) Cleanup() error {
+	return a.deleteDir(a.WorkingDir)
 }
 
 // extractFile extracts given archive file into given output directory
@@ -98,21 +134,6 @@ func (a *Archive) extract() error {
 	return a.extractFile(a.Path, a.WorkingDir)
 }
 
-// selectRoms filters all roms found in working directory and copy selected ones to output directory
-func (a *Archive) selectRoms() error {
-	// process input files
-	if err := a.processDir(a.WorkingDir); err != nil {
-		return err
-	}
-
-	// move selected files to output directory
-	if err := a.moveSelectedRoms(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // deleteDir deletes given directory files
 func (a *Archive) deleteDir(dir string) error {
 	if a.Options.Debug {
@@ -122,11 +143,6 @@ func (a *Archive) deleteDir(dir string) error {
 	return os.RemoveAll(dir)
 }
 
-// cleanup deletes all extracted files
-func (a *Archive) cleanup() error {
-	return a.deleteDir(a.WorkingDir)
-}
-
 // processDir processes files in given directory
 func (a *Archive) processDir(inputDir string) error {
 	files, err := ioutil.ReadDir(inputDir)
@@ -159,7 +175,7 @@ func (a *Archive) processFile(inputDir string, file os.FileInfo) error {
 
 	// check file type
 	fileExt := filepath.Ext(file.Name())
-	if (fileExt != ".zip") && (fileExt != ".7z") {
+	if (fileExt != ".zip") && (fileExt != ".7z") && (romExtensions[strings.ToLower(fileExt)] == "") {
 		// skip file
 		return nil
 	}
@@ -181,22 +197,29 @@ func (a *Archive) processGameFile(filePath string) error {
 		return err
 	}
 
+	a.Seen++
+	r.Skipper = a.System.Skipper
+	a.System.findPatch(r)
+
 	if skip, msg := a.skip(r); skip {
 		if a.Options.Debug {
 			a.log(fmt.Sprintf("Skipped '%s': %s\n", r.Filename, msg))
 		}
 
 		a.Skipped++
+		a.System.addSkip(r, msg)
 
 		return nil
 	}
 
-	if a.Games[r.Name] == nil {
+	key := rom.NormalizeTitle(r.Name)
+
+	if a.Games[key] == nil {
 		// it's a new game
-		a.Games[r.Name] = rom.NewGame()
+		a.Games[key] = rom.NewGame()
 	}
 
-	a.Games[r.Name].AddRom(r)
+	a.Games[key].AddRom(r)
 
 	return nil
 }
@@ -210,21 +233,12 @@ func (a *Archive) processGameArchive(filePath string) error {
 		return err
 	}
 
-	// select one rom from game archive
-	if err := a.selectGameArchiveRom(gamesDir); err != nil {
-		return err
-	}
-
-	// delete extracted game archive
-	if err := a.deleteDir(gamesDir); err != nil {
-		return err
-	}
-
-	return nil
+	// select candidate roms from game archive
+	return a.selectGameArchiveRoms(gamesDir)
 }
 
-// selectGameArchiveRom selects only one rom from given game archive directory, mark it as already selected then copy it to output
-func (a *Archive) selectGameArchiveRom(dir string) error {
+// selectGameArchiveRoms keeps only the best roms from given game archive directory, and deletes the other ones
+func (a *Archive) selectGameArchiveRoms(dir string) error {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return err
@@ -244,12 +258,17 @@ func (a *Archive) selectGameArchiveRom(dir string) error {
 				return err
 			}
 
+			a.Seen++
+			r.Skipper = a.System.Skipper
+			a.System.findPatch(r)
+
 			if skip, msg := a.skip(r); skip {
 				if a.Options.Debug {
 					a.log(fmt.Sprintf("Skipped '%s': %s\n", r.Filename, msg))
 				}
 
 				a.Skipped++
+				a.System.addSkip(r, msg)
 			} else {
 				g.AddRom(r)
 			}
@@ -258,100 +277,42 @@ func (a *Archive) selectGameArchiveRom(dir string) error {
 		}
 	}
 
-	// select best rom
-	if err := a.moveGameBestRom(g); err != nil {
-		return err
-	}
-
-	gName, _ := rom.NameAndRegions(path.Base(dir))
-	a.Games[gName] = g
-
-	return nil
-}
-
-// skip returns true if given rom must be skiped, with an explanation message
-func (a *Archive) skip(r *rom.Rom) (bool, string) {
-	if a.Options.Strict && !r.HaveRegion(a.Options.Regions) {
-		return true, fmt.Sprintf("Strict: %v", r.Regions)
-	}
-
-	if r.Proto && !a.Options.KeepProto {
-		return true, "Ignore proto"
-	}
-
-	if r.Beta && !a.Options.KeepBeta {
-		return true, "Ignore beta"
-	}
-
-	if r.Bios {
-		return true, "Ignore bios"
-	}
-
-	if r.Sample && !a.Options.KeepSample {
-		return true, "Ignore sample"
-	}
-
-	if r.Demo && !a.Options.KeepDemo {
-		return true, "Ignore demo"
-	}
-
-	if r.Pirate && !a.Options.KeepPirate {
-		return true, "Ignore pirate"
-	}
-
-	if r.Promo && !a.Options.KeepPromo {
-		return true, "Ignore promo"
-	}
-
-	return false, ""
-}
-
-// moveFile moves given file into given directory
-func (a *Archive) moveFile(filePath string, dir string) error {
-	if a.Options.Debug {
-		a.log(fmt.Sprintf("Moving '%s' into: %s\n", filePath, dir))
-	}
-
-	return os.Rename(filePath, dir)
-}
+	// keep best roms only
+	best := a.System.bestRoms(g)
 
-// moveGameBestRom moves best rom of given game to output directory
-func (a *Archive) moveGameBestRom(g *rom.Game) error {
-	if g.Moved {
-		// game was already moved
-		return nil
+	for _, r := range g.Roms {
+		if !containsRom(best, r) {
+			if err := os.Remove(r.File); err != nil {
+				return err
+			}
+		}
 	}
 
-	r := g.BestRom(a.Options.Regions)
-	if r == nil {
+	if len(best) == 0 {
 		// no rom matches filtering criteria
-		return nil
+		return a.deleteDir(dir)
 	}
 
-	outputPath := path.Join(a.Output, r.Filename)
+	g.Roms = best
 
-	if err := a.moveFile(r.File, outputPath); err != nil {
-		return err
-	}
-
-	g.Moved = true
-
-	a.RegionsStats[r.BestRegion(a.Options.Regions)]++
+	gName, _ := rom.NameAndRegions(path.Base(dir))
+	a.Games[rom.NormalizeTitle(gName)] = g
 
 	return nil
 }
 
-// moveSelectedRoms moves selected roms to output directory
-func (a *Archive) moveSelectedRoms() error {
-	if a.Options.Debug {
-		a.log(fmt.Sprintf("Moving all %v selected roms to: %s\n", len(a.Games), a.Output))
-	}
+// skip returns true if given rom must be skiped, with an explanation message
+func (a *Archive) skip(r *rom.Rom) (bool, string) {
+	return OverrideSkip(r, a.Options, a.System.override(r.Name))
+}
 
-	for _, g := range a.Games {
-		if err := a.moveGameBestRom(g); err != nil {
-			return err
+// containsRom returns true if given rom is in given list
+func containsRom(roms []*rom.Rom, r *rom.Rom) bool {
+	for _, v := range roms {
+		if v == r {
+			return true
 		}
 	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,102 @@
+package system
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/romheader"
+)
+
+// HeaderCheck represents the result of a rom internal header inspection
+type HeaderCheck struct {
+	// inspected header, nil if none was found
+	Header *romheader.Header
+
+	// problems found: wrong system, bad checksums
+	Issues []string
+
+	// region guessed from header for roms tagged "(Unknown)", empty if none
+	Region string
+}
+
+// CheckRom reads the internal header of given rom file, and checks it against given systems names. N64 roms are expected in the byte order set with the -n64-format option, or in big endian like in no-intro sets. Returns nil if the file can't be inspected: 7z archives, or roms which header format is not supported.
+func CheckRom(r *rom.Rom, systemNames []string, options *core.Options) (*HeaderCheck, error) {
+	if strings.ToLower(filepath.Ext(r.File)) == ".7z" {
+		return nil, nil
+	}
+
+	data, _, err := helpers.ReadRom(r.File, helpers.MaxRomSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > helpers.MaxRomSize {
+		return nil, nil
+	}
+
+	h := romheader.Parse(data)
+	if h == nil {
+		return nil, nil
+	}
+
+	result := &HeaderCheck{Header: h}
+
+	if !stringIn(systemNames, h.System) && headerSupported(systemNames) {
+		result.Issues = append(result.Issues, fmt.Sprintf("header says %s", h.System))
+	}
+
+	result.Issues = append(result.Issues, h.Errors...)
+
+	if h.System == romheader.SystemN64 {
+		expected := options.N64Format
+		if expected == "" {
+			expected = romheader.N64BigEndian
+		}
+
+		if h.Format != expected {
+			result.Issues = append(result.Issues, fmt.Sprintf("byte order %s (expected %s)", h.Format, expected))
+		}
+	}
+
+	if (h.Region != "") && stringIn(r.Regions, "Unknown") {
+		result.Region = h.Region
+	}
+
+	return result, nil
+}
+
+// String returns a description of found issues and region guess
+func (c *HeaderCheck) String() string {
+	msgs := append([]string{}, c.Issues...)
+	if c.Region != "" {
+		msgs = append(msgs, fmt.Sprintf("region guess: %s", c.Region))
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// headerSupported returns true if the header of one of given systems can be parsed, so that a header of another system is a mismatch
+func headerSupported(systemNames []string) bool {
+	for _, name := range romheader.Systems() {
+		if stringIn(systemNames, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringIn returns true if given string is in given list
+func stringIn(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package system
+
+import (
+	"github.com/aymerick/charette/curated"
+	"github.com/aymerick/charette/rom"
+)
+
+// FilterGame returns true if given game must be left out given curated lists of games to include and to exclude, with an explanation message. Systems without entries in include list are not filtered by that list.
+func FilterGame(g *rom.Game, include *curated.List, exclude *curated.List, systemNames []string) (bool, string) {
+	if len(g.Roms) == 0 {
+		return false, ""
+	}
+
+	name := g.Roms[0].Name
+
+	var crcs []string
+	if include.HaveCRC(systemNames) || exclude.HaveCRC(systemNames) {
+		crcs = gameCRCs(g)
+	}
+
+	if e := exclude.Find(systemNames, name, crcs); e != nil {
+		e.Matched = true
+		return true, "In exclude list"
+	}
+
+	if include.Covers(systemNames) {
+		e := include.Find(systemNames, name, crcs)
+		if e == nil {
+			return true, "Not in include list"
+		}
+
+		e.Matched = true
+	}
+
+	return false, ""
+}
+
+// gameCRCs returns the CRC32 of all given game roms
+func gameCRCs(g *rom.Game) []string {
+	result := []string{}
+
+	for _, r := range g.Roms {
+		if crc, err := r.ComputeCRC(); err == nil {
+			result = append(result, crc)
+		}
+	}
+
+	return result
+}
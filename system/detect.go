@@ -0,0 +1,234 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/romheader"
+)
+
+const (
+	// number of roms which headers are checked
+	detectSamples = 3
+
+	// confidence scores
+	datScore       = 100
+	headerScore    = 30
+	extensionScore = 40
+
+	// minimum confidence to use a detected system
+	MinConfidence = 50
+)
+
+// Detection represents a system guessed from archive contents
+type Detection struct {
+	// detected system
+	Infos Infos
+
+	// confidence, from 0 to 100
+	Confidence int
+
+	// clues that lead to that system
+	Reasons []string
+}
+
+// Level returns the confidence level: "high", "medium" or "low"
+func (d *Detection) Level() string {
+	switch {
+	case d.Confidence >= 80:
+		return "high"
+	case d.Confidence >= MinConfidence:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// String implements fmt.Stringer
+func (d *Detection) String() string {
+	return fmt.Sprintf("%s (confidence: %s %d%%, %s)", d.Infos.FullName(), d.Level(), d.Confidence, strings.Join(d.Reasons, ", "))
+}
+
+// detector collects clues about the system of an archive
+type detector struct {
+	// scores indexed by system full name
+	scores map[string]int
+
+	// clues indexed by system full name
+	reasons map[string][]string
+
+	// rom extensions count indexed by system full name
+	extensions map[string]int
+
+	// number of roms with a known extension or not
+	roms int
+}
+
+// DetectArchive guesses the system of given archive or extracted directory from its contents: DAT files inside the pack, rom file extensions and rom header signatures. Files are extracted into given tmp directory. Returns nil if no system was found.
+func DetectArchive(filePath string, tmp string) (*Detection, error) {
+	d := &detector{
+		scores:     map[string]int{},
+		reasons:    map[string][]string{},
+		extensions: map[string]int{},
+	}
+
+	entries, err := helpers.ListArchive(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Join(tmp, "detect")
+	defer os.RemoveAll(dir)
+
+	samples := 0
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Path))
+
+		if dat.IsDatFile(entry.Path) {
+			d.checkDat(filePath, entry.Path, dir)
+			continue
+		}
+
+		if (ext != ".zip") && (ext != ".7z") {
+			d.addExtension(entry.Path)
+		}
+
+		// readme files and the like are not worth a sample
+		if ((ext == ".zip") || (romExtensions[ext] != "")) && (samples < detectSamples) {
+			samples++
+			d.checkHeader(filePath, entry.Path, dir)
+		}
+	}
+
+	return d.result(), nil
+}
+
+// checkDat adds DAT header clue from given DAT file in given archive
+func (d *detector) checkDat(archive string, entry string, dir string) {
+	filePath, err := extractEntry(archive, entry, dir)
+	if err != nil {
+		return
+	}
+
+	df, err := dat.Load(filePath)
+	if err != nil {
+		return
+	}
+
+	if infos, found := InfosForName(df.Header.Name); found {
+		d.add(infos.FullName(), datScore, fmt.Sprintf("DAT header '%s'", df.Header.Name))
+	}
+}
+
+// checkHeader adds header signature clue from given rom file in given archive
+func (d *detector) checkHeader(archive string, entry string, dir string) {
+	filePath, err := extractEntry(archive, entry, dir)
+	if err != nil {
+		return
+	}
+
+	data, name, err := helpers.ReadRom(filePath, romheader.MinSize)
+	if err != nil {
+		return
+	}
+
+	if name != path.Base(entry) {
+		// zipped rom
+		d.addExtension(name)
+	}
+
+	if system := romheader.Detect(data); system != "" {
+		d.add(system, headerScore, "header of "+path.Base(name))
+	}
+}
+
+// addExtension counts rom extension of given file name
+func (d *detector) addExtension(fileName string) {
+	d.roms++
+
+	if system := romExtensions[strings.ToLower(filepath.Ext(fileName))]; system != "" {
+		d.extensions[system]++
+	}
+}
+
+// add adds given score for given system, with given reason
+func (d *detector) add(system string, score int, reason string) {
+	d.scores[system] += score
+	d.reasons[system] = append(d.reasons[system], reason)
+}
+
+// result returns the system with the best score, or nil if none
+func (d *detector) result() *Detection {
+	for system, nb := range d.extensions {
+		d.add(system, extensionScore*nb/d.roms, fmt.Sprintf("extension of %d/%d roms", nb, d.roms))
+	}
+
+	systems := []string{}
+	for system := range d.scores {
+		if _, found := SupportedSystemsMap[system]; found {
+			systems = append(systems, system)
+		}
+	}
+
+	if len(systems) == 0 {
+		return nil
+	}
+
+	// best score first, then by name for consistency
+	sort.Strings(systems)
+	sort.Stable(systemsByScore{systems, d.scores})
+
+	best := systems[0]
+
+	confidence := d.scores[best]
+	if confidence > 100 {
+		confidence = 100
+	}
+
+	return &Detection{
+		Infos:      SupportedSystemsMap[best],
+		Confidence: confidence,
+		Reasons:    d.reasons[best],
+	}
+}
+
+// extractEntry extracts given entry from given archive into given directory, and returns the extracted file path. An entry of an extracted directory is not copied.
+func extractEntry(archive string, entry string, dir string) (string, error) {
+	if helpers.IsDir(archive) {
+		return path.Join(archive, entry), nil
+	}
+
+	if err := helpers.ExecCmd("7z", []string{"e", archive, "-o" + dir, entry, "-y"}); err != nil {
+		return "", err
+	}
+
+	return path.Join(dir, path.Base(entry)), nil
+}
+
+// systemsByScore sorts systems names by score, best first
+type systemsByScore struct {
+	systems []string
+	scores  map[string]int
+}
+
+// Implements sort.Interface
+func (a systemsByScore) Len() int {
+	return len(a.systems)
+}
+
+// Implements sort.Interface
+func (a systemsByScore) Swap(i, j int) {
+	a.systems[i], a.systems[j] = a.systems[j], a.systems[i]
+}
+
+// Implements sort.Interface
+func (a systemsByScore) Less(i, j int) bool {
+	return a.scores[a.systems[i]] > a.scores[a.systems[j]]
+}
@@ -0,0 +1,146 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestDetectorResult(t *testing.T) {
+	const gb = "Nintendo - Game Boy"
+	const nes = "Nintendo - Nintendo Entertainment System"
+
+	tests := []struct {
+		files      []string
+		headers    []string
+		dats       []string
+		system     string
+		confidence int
+		reasons    []string
+	}{
+		// extensions only
+		{
+			[]string{"Tetris (World).gb", "Kwirk (USA).gb", "readme.txt", "Tetris DX (World).gbc"},
+			nil,
+			nil,
+			gb,
+			20,
+			[]string{"extension of 2/4 roms"},
+		},
+		// headers win over extensions
+		{
+			[]string{"Tetris DX (World).gbc", "Pokemon - Yellow (USA).gbc"},
+			[]string{gb, gb},
+			nil,
+			gb,
+			60,
+			[]string{"header of Tetris DX (World).gbc", "header of Pokemon - Yellow (USA).gbc"},
+		},
+		// DAT header is a sure clue
+		{
+			[]string{"Tetris (World).gb"},
+			[]string{gb},
+			[]string{nes},
+			nes,
+			100,
+			[]string{"DAT header"},
+		},
+		// unsupported system
+		{
+			[]string{"Game.bin"},
+			[]string{"Unknown"},
+			nil,
+			"",
+			0,
+			nil,
+		},
+		// same score, by name
+		{
+			[]string{"Tetris (World).gb", "Tetris DX (World).gbc"},
+			nil,
+			nil,
+			gb,
+			20,
+			[]string{"extension of 1/2 roms"},
+		},
+	}
+
+	for i, test := range tests {
+		d := &detector{
+			scores:     map[string]int{},
+			reasons:    map[string][]string{},
+			extensions: map[string]int{},
+		}
+
+		for j, fileName := range test.files {
+			d.addExtension(fileName)
+
+			if j < len(test.headers) {
+				d.add(test.headers[j], headerScore, "header of "+fileName)
+			}
+		}
+
+		for _, system := range test.dats {
+			d.add(system, datScore, "DAT header")
+		}
+
+		result := d.result()
+		if result == nil {
+			if test.system != "" {
+				t.Errorf("Detection #%d failed, got nothing but expected %s", i+1, test.system)
+			}
+			continue
+		}
+
+		if result.Infos.FullName() != test.system {
+			t.Errorf("Detection #%d failed, got %s but expected %s", i+1, result.Infos.FullName(), test.system)
+		}
+
+		if result.Confidence != test.confidence {
+			t.Errorf("Detection #%d failed, got confidence %d but expected %d", i+1, result.Confidence, test.confidence)
+		}
+
+		if !reflect.DeepEqual(result.Reasons, test.reasons) {
+			t.Errorf("Detection #%d failed, got reasons %v but expected %v", i+1, result.Reasons, test.reasons)
+		}
+	}
+}
+
+func TestDetectArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pack := path.Join(dir, "pack")
+	os.MkdirAll(pack, 0777)
+
+	files := map[string][]byte{
+		// readme files are not sampled for headers
+		"A.txt":              []byte("readme"),
+		"B.txt":              []byte("readme"),
+		"C.txt":              []byte("readme"),
+		"Kwirk (USA).nes":    []byte("NES\x1a\x02\x01"),
+		"Nintendo - NES.dat": []byte("<datafile><header><name>Nintendo - Nintendo Entertainment System (20200101)</name></header></datafile>"),
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(path.Join(pack, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := DetectArchive(pack, path.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"header of Kwirk (USA).nes", "DAT header 'Nintendo - Nintendo Entertainment System (20200101)'", "extension of 1/4 roms"}
+
+	if (result == nil) || (result.Infos.FullName() != "Nintendo - Nintendo Entertainment System") || !reflect.DeepEqual(result.Reasons, expected) {
+		t.Errorf("Failed to detect archive system, got %v but expected reasons %v", result, expected)
+	}
+}
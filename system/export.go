@@ -0,0 +1,91 @@
+package system
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+)
+
+// ExportDat returns a DAT of selected roms, one DAT game per selected rom and per patched copy. Roms are described with entries from system DATs when found, so that hashes are included.
+func (s *System) ExportDat() *dat.Dat {
+	result := dat.New()
+
+	result.Header = dat.Header{
+		Name:        s.Infos.FullName(),
+		Description: s.Infos.FullName() + " (1G1R)",
+		Version:     time.Now().Format("20060102-150405"),
+		Author:      "charette",
+		Comment:     "charette " + s.Options.SelectionFlags(),
+	}
+
+	selected := map[string]*rom.Rom{}
+	names := []string{}
+
+	for _, g := range s.Games {
+		for _, r := range g.OutputRoms() {
+			name := helpers.FileBase(r.Filename)
+
+			if selected[name] == nil {
+				selected[name] = r
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		result.Games = append(result.Games, s.datGame(name, selected[name]))
+	}
+
+	return result
+}
+
+// datGame returns the DAT game describing given selected rom: the entry from system DATs if found, otherwise a game without hashes. A converted or patched rom is described as written to output directory, with its size and CRC32.
+func (s *System) datGame(name string, r *rom.Rom) *dat.Game {
+	if g := s.findDatGame(name, r); g != nil {
+		return g
+	}
+
+	result := &dat.Game{
+		Name:        name,
+		Description: name,
+		Roms:        []*dat.Rom{{Name: r.Filename}},
+	}
+
+	if r.DumpCRC != "" {
+		if crc, err := r.ComputeCRC(); err == nil {
+			result.Roms[0].CRC = crc
+		}
+
+		if size, err := r.ComputeSize(); err == nil {
+			result.Roms[0].Size = size
+		}
+	}
+
+	return result
+}
+
+// findDatGame returns the game of system DATs with given name or with given rom CRC32, or nil if not found. DAT entries don't describe a converted or patched rom, so nil is returned for it.
+func (s *System) findDatGame(name string, r *rom.Rom) *dat.Game {
+	if r.DumpCRC != "" {
+		return nil
+	}
+
+	for _, d := range s.Dats {
+		if g := d.FindGame(name); g != nil {
+			return g
+		}
+
+		if r.CRC != "" {
+			if g, _ := d.FindCRC(r.CRC); g != nil {
+				return g
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package system
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/aymerick/charette/core"
+)
+
+// Group represents several systems sharing the same output directory, eg: "Bandai - WonderSwan" and "Bandai - WonderSwan Color" in "wswan"
+type Group struct {
+	// output directory name
+	Dir string
+
+	// options
+	Options *core.Options
+
+	// systems sharing that output directory, sorted by preference
+	Systems []*System
+
+	// moved files, indexed by file name
+	files map[string]*System
+}
+
+// NewGroup instanciates a new Group
+func NewGroup(dir string, options *core.Options) *Group {
+	return &Group{
+		Dir:     dir,
+		Options: options,
+		files:   map[string]*System{},
+	}
+}
+
+// AddSystem adds a system to that group
+func (g *Group) AddSystem(s *System) {
+	s.Group = g
+
+	g.Systems = append(g.Systems, s)
+	sort.Stable(systemsByPreference{g.Systems, g.preferences()})
+}
+
+// preferences returns the preferred systems order, user preferences first
+func (g *Group) preferences() []string {
+	return append(append([]string{}, g.Options.PreferredSystems...), DirPreferences[g.Dir]...)
+}
+
+// Dedup removes games selected in several systems of that group, keeping them only in the preferred system. Roms to select are computed first, so a game is only removed when the preferred system actually selects it.
+func (g *Group) Dedup() {
+	if len(g.Systems) < 2 {
+		return
+	}
+
+	for _, s := range g.Systems {
+		s.PlanRoms()
+	}
+
+	for i, s := range g.Systems {
+		for key, game := range s.Games {
+			if len(game.Selected) == 0 {
+				continue
+			}
+
+			for _, other := range g.Systems[i+1:] {
+				if dup := other.Games[key]; (dup != nil) && (len(dup.Selected) > 0) {
+					if !g.Options.Quiet {
+						other.log(fmt.Sprintf("Skipped duplicate '%s': already found in %s\n", dup.Name, s.Infos.FullName()))
+					}
+
+					delete(other.Games, key)
+					other.Duplicates++
+					other.duplicates[key] = &ReportEntry{Name: dup.Name, Status: StatusHave, Reason: fmt.Sprintf("Selected from %s", s.Infos.FullName())}
+				}
+			}
+		}
+	}
+}
+
+// claim registers a file moved by given system, and warns if that file was already moved by another system
+func (g *Group) claim(s *System, fileName string) {
+	if other := g.files[fileName]; (other != nil) && (other != s) {
+		s.log(fmt.Sprintf("WARN: '%s' overwrites file from %s in: %s\n", fileName, other.Infos.FullName(), path.Join(g.Options.Output, g.Dir)))
+	}
+
+	g.files[fileName] = s
+}
+
+// systemsByPreference sorts systems given preferred systems names
+type systemsByPreference struct {
+	systems     []*System
+	preferences []string
+}
+
+// Implements sort.Interface
+func (a systemsByPreference) Len() int {
+	return len(a.systems)
+}
+
+// Implements sort.Interface
+func (a systemsByPreference) Swap(i, j int) {
+	a.systems[i], a.systems[j] = a.systems[j], a.systems[i]
+}
+
+// Implements sort.Interface
+func (a systemsByPreference) Less(i, j int) bool {
+	return a.rank(a.systems[i]) < a.rank(a.systems[j])
+}
+
+// rank returns the preference rank of given system
+func (a systemsByPreference) rank(s *System) int {
+	for i, name := range a.preferences {
+		if (name == s.Infos.FullName()) || (name == s.Infos.Name) {
+			return i
+		}
+	}
+
+	return len(a.preferences)
+}
@@ -0,0 +1,150 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/skipper"
+)
+
+// skipperAliases holds the output directories of systems which no-intro header skipper definitions are not named after, indexed by definition key
+var skipperAliases = map[string]string{
+	"a7800": "atari7800",
+	"lnx":   "lynx",
+}
+
+// FindSkipper returns the header skipper of given system, or nil if none. The skipper referenced by a system DAT wins, then a skipper named after the system is used, eg: "No-Intro_NES.xml".
+func FindSkipper(infos Infos, detectors []*skipper.Detector, dats []*dat.Dat) *skipper.Detector {
+	for _, d := range dats {
+		if file := d.SkipperFile(); file != "" {
+			for _, detector := range detectors {
+				if detector.Is(file) {
+					return detector
+				}
+			}
+		}
+	}
+
+	for _, detector := range detectors {
+		key := detector.Key()
+		if alias := skipperAliases[key]; alias != "" {
+			key = alias
+		}
+
+		if found, ok := FindInfos(key); ok && (found.Dir == infos.Dir) {
+			return detector
+		}
+	}
+
+	return nil
+}
+
+// SystemDats returns the DATs of given system
+func SystemDats(infos Infos, dats []*dat.Dat) []*dat.Dat {
+	result := []*dat.Dat{}
+
+	for _, d := range dats {
+		if found, ok := InfosForName(d.Header.Name); ok && (found == infos) {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+// HeaderAction returns the header action of that system, as set with the -headers option
+func (s *System) HeaderAction() string {
+	for _, name := range s.Names() {
+		for key, action := range s.Options.HeaderActions {
+			if strings.EqualFold(key, name) {
+				return action
+			}
+		}
+	}
+
+	return core.HeaderKeep
+}
+
+// datHeader returns the header of given headerless rom data found in system DATs, or nil if none
+func (s *System) datHeader(data []byte) []byte {
+	crc := fmt.Sprintf("%08X", crc32.ChecksumIEEE(data))
+
+	for _, d := range s.Dats {
+		if _, r := d.FindCRC(crc); r != nil {
+			if header := r.HeaderBytes(); header != nil {
+				return header
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatHeader adds, strips or normalizes the header of given rom file, as set with the -headers option. Zipped roms are rewritten.
+func (s *System) formatHeader(r *rom.Rom) error {
+	action := s.HeaderAction()
+	if (action == core.HeaderKeep) || (s.Skipper == nil) || (strings.ToLower(filepath.Ext(r.File)) == ".7z") {
+		return nil
+	}
+
+	data, name, err := helpers.ReadRom(r.File, helpers.MaxRomSize+1)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > helpers.MaxRomSize {
+		s.log(fmt.Sprintf("WARN: Rom too big, header not changed: %s\n", r.Filename))
+		return nil
+	}
+
+	header, body := s.Skipper.Split(data)
+
+	var result []byte
+
+	switch action {
+	case core.HeaderStrip:
+		if header == nil {
+			return nil
+		}
+
+		result = body
+
+	case core.HeaderAdd, core.HeaderNormalize:
+		if (action == core.HeaderAdd) && (header != nil) {
+			return nil
+		}
+
+		datHeader := s.datHeader(body)
+		if datHeader == nil {
+			if header == nil {
+				s.log(fmt.Sprintf("WARN: No header found in DATs for: %s\n", r.Filename))
+			}
+
+			return nil
+		}
+
+		if bytes.Equal(header, datHeader) {
+			return nil
+		}
+
+		result = append(datHeader, body...)
+	}
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Header %s: %s\n", action, r.Filename))
+	}
+
+	if strings.ToLower(filepath.Ext(r.File)) == ".zip" {
+		return helpers.WriteZip(r.File, name, result)
+	}
+
+	return ioutil.WriteFile(r.File, result, 0666)
+}
@@ -2,9 +2,20 @@ package system
 
 import (
 	"path"
+	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/helpers"
 )
 
+// rTag matches a tag in a file name, eg: "(USA, Europe)"
+var rTag = regexp.MustCompile(`\(([^\(\)]*)\)`)
+
+// rDate matches the set date in no-intro archive names, eg: "(20240101-123456)"
+var rDate = regexp.MustCompile(`\((\d{8}(?:-\d{6})?)\)`)
+
 // Infos represents infos about a gaming system
 type Infos struct {
 	Manufacturer string
@@ -18,6 +29,9 @@ var SupportedSystems []Infos
 // SupportedSystems holds infos for all supported systems, indexed by "<Manufacturer> - <Name>"
 var SupportedSystemsMap map[string]Infos
 
+// DirPreferences holds the preferred systems order for output directories shared by several systems, used when a game is found in several of them
+var DirPreferences map[string][]string
+
 func init() {
 	SupportedSystems = []Infos{
 		{"Atari", "5200", "atari2600"},
@@ -80,22 +94,160 @@ func init() {
 		{"Watara", "Supervision", "supervision"},
 	}
 
+	DirPreferences = map[string][]string{
+		"c64":      {"Commodore - 64", "Commodore - 64 (PP)", "Commodore - 64 (Tapes)"},
+		"msx":      {"Microsoft - MSX 2", "Microsoft - MSX"},
+		"ngp":      {"SNK - Neo Geo Pocket Color", "SNK - Neo Geo Pocket"},
+		"pcengine": {"NEC - PC Engine - TurboGrafx 16", "NEC - Super Grafx"},
+		"wswan":    {"Bandai - WonderSwan Color", "Bandai - WonderSwan"},
+	}
+
 	SupportedSystemsMap = make(map[string]Infos)
 	for _, infos := range SupportedSystems {
-		SupportedSystemsMap[infos.Manufacturer+" - "+infos.Name] = infos
+		SupportedSystemsMap[infos.FullName()] = infos
 	}
 }
 
-// InfosForArchive returns system informations corresponding to archive name, the second value returned is `false` if system was not found
+// FullName returns the "<Manufacturer> - <Name>" system name
+func (infos Infos) FullName() string {
+	return infos.Manufacturer + " - " + infos.Name
+}
+
+// Names returns the names that identify that system in settings: full name, name and output directory
+func (infos Infos) Names() []string {
+	return []string{infos.FullName(), infos.Name, infos.Dir}
+}
+
+// InfosForArchive returns system informations corresponding to archive name, eg: "Nintendo - Game Boy (Parent-Clone) (20240101-123456).7z", the second value returned is `false` if system was not found
 func InfosForArchive(filePath string) (Infos, bool) {
-	var result Infos
-	found := false
+	name := helpers.FileBase(path.Base(filePath))
+	if !strings.Contains(name, " (") {
+		return Infos{}, false
+	}
+
+	return InfosForName(name)
+}
+
+// FindInfos returns system informations for given system full name, name or output directory, with a case insensitive lookup. The second value returned is `false` if system was not found
+func FindInfos(name string) (Infos, bool) {
+	for _, infos := range SupportedSystems {
+		if strings.EqualFold(name, infos.FullName()) || strings.EqualFold(name, infos.Name) || strings.EqualFold(name, infos.Dir) {
+			return infos, true
+		}
+	}
+
+	return Infos{}, false
+}
+
+// HasRegionTag returns true if given file name looks like a no-intro rom name, with a regions tag
+func HasRegionTag(fileName string) bool {
+	for _, match := range rTag.FindAllStringSubmatch(fileName, -1) {
+		if len(core.ParseRegions(match[1])) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ArchiveDate returns the set date found in given archive name, eg: "20240101-123456" for "Nintendo - Game Boy (20240101-123456).7z", or an empty string if not found. Dates can be compared as strings.
+func ArchiveDate(filePath string) string {
+	match := rDate.FindAllStringSubmatch(path.Base(filePath), -1)
+	if len(match) == 0 {
+		return ""
+	}
+
+	return match[len(match)-1][1]
+}
+
+// SortArchives sorts given archives paths by set date, newest first
+func SortArchives(archives []string) {
+	sort.Stable(archivesByDate(archives))
+}
 
-	arr := strings.Split(path.Base(filePath), " (")
-	if len(arr) == 2 {
-		result = SupportedSystemsMap[arr[0]]
-		found = true
+// NewestArchives returns the archives with the newest set date in given archives paths, and the older ones
+func NewestArchives(archives []string) ([]string, []string) {
+	newest := ""
+	for _, archive := range archives {
+		if date := ArchiveDate(archive); date > newest {
+			newest = date
+		}
 	}
 
-	return result, found
+	result := []string{}
+	older := []string{}
+
+	for _, archive := range archives {
+		if ArchiveDate(archive) == newest {
+			result = append(result, archive)
+		} else {
+			older = append(older, archive)
+		}
+	}
+
+	return result, older
+}
+
+// InfosForName returns system informations corresponding to given "<Manufacturer> - <Name>" system name, ignoring trailing tags like " (Parent-Clone)". The second value returned is `false` if system was not found
+func InfosForName(name string) (Infos, bool) {
+	for {
+		if result, found := SupportedSystemsMap[name]; found {
+			return result, true
+		}
+
+		i := strings.LastIndex(name, " (")
+		if i < 0 {
+			return Infos{}, false
+		}
+
+		name = name[:i]
+	}
+}
+
+// SortedInfos returns the systems of given archives map, sorted by name
+func SortedInfos(systems map[Infos][]string) []Infos {
+	result := []Infos{}
+	for infos := range systems {
+		result = append(result, infos)
+	}
+
+	sort.Sort(infosByName(result))
+
+	return result
+}
+
+// infosByName sorts systems by name
+type infosByName []Infos
+
+// Implements sort.Interface
+func (a infosByName) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a infosByName) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a infosByName) Less(i, j int) bool {
+	return a[i].FullName() < a[j].FullName()
+}
+
+// archivesByDate sorts archives paths by set date, newest first
+type archivesByDate []string
+
+// Implements sort.Interface
+func (a archivesByDate) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a archivesByDate) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a archivesByDate) Less(i, j int) bool {
+	return ArchiveDate(a[i]) > ArchiveDate(a[j])
 }
@@ -0,0 +1,223 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+)
+
+// rSave matches the extension of a save file that follows the rom name: battery saves, save states and their slots, eg: ".srm", ".state1", ".state.auto"
+var rSave = regexp.MustCompile(`^\.(srm|sav|state\d*|state\.auto)$`)
+
+// IsSaveFile returns true if given file name has a save file extension
+func IsSaveFile(fileName string) bool {
+	return rSave.MatchString(strings.ToLower(filepath.Ext(fileName))) || strings.HasSuffix(strings.ToLower(fileName), ".state.auto")
+}
+
+// scanPrevious indexes roms already present in output directory by normalized game name, so that save files of replaced roms can be migrated
+func (s *System) scanPrevious() error {
+	s.previous = map[string][]*rom.Rom{}
+
+	files, err := ioutil.ReadDir(s.OutputDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !isRomFile(file.Name()) || !HasRegionTag(file.Name()) {
+			continue
+		}
+
+		r := rom.New(path.Join(s.OutputDir(), file.Name()))
+		if err := r.Fill(); err != nil {
+			return err
+		}
+
+		key := rom.NormalizeTitle(r.Name)
+		s.previous[key] = append(s.previous[key], r)
+	}
+
+	return nil
+}
+
+// isRomFile returns true if given file name has a rom extension, or is a zip or 7z archive
+func isRomFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	return (ext == ".zip") || (ext == ".7z") || (romExtensions[ext] != "")
+}
+
+// migrateSaves renames save files of roms previously selected for given game to the names of the newly selected roms, and records renamed rom files so that gamelist entries follow
+func (s *System) migrateSaves(g *rom.Game) error {
+	if (len(g.Selected) == 0) || (len(s.previous) == 0) {
+		return nil
+	}
+
+	for _, old := range s.replacedRoms(g) {
+		r := g.Selected[0]
+		for _, selected := range g.Selected {
+			if selected.Part == old.Part {
+				r = selected
+			}
+		}
+
+		done, err := s.alreadyMigrated(old, r)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			continue
+		}
+
+		renamed, err := s.renameSaves(helpers.FileBase(old.Filename), helpers.FileBase(r.Filename))
+		if err != nil {
+			return err
+		}
+
+		s.Renamed[old.Filename] = r.Filename
+
+		if s.Options.Playlists && (len(g.Selected) > 1) && (old.Part != "") {
+			// saves of a multi-part release are named after its playlist
+			more, err := s.renameSaves(old.ReleaseName(), r.ReleaseName())
+			if err != nil {
+				return err
+			}
+
+			renamed += more
+			s.Renamed[old.ReleaseName()+".m3u"] = r.ReleaseName() + ".m3u"
+		}
+
+		if renamed == 0 {
+			continue
+		}
+
+		msg := fmt.Sprintf("%s -> %s", old.Filename, r.Filename)
+
+		if strings.Join(old.Regions, ",") != strings.Join(r.Regions, ",") {
+			msg += " (region changed, save format may not be compatible)"
+			s.log(fmt.Sprintf("WARN: Saves of '%s' migrated to '%s', but save format may not be compatible across regions\n", old.Filename, r.Filename))
+		}
+
+		s.Migrated = append(s.Migrated, msg)
+	}
+
+	return nil
+}
+
+// alreadyMigrated returns true if saves of given previous rom were already migrated to given selected rom by a previous run. Replaced roms are kept in output directory, so they are found again on each run: the migration is skipped when the selected rom was already in output directory, or already has save files.
+func (s *System) alreadyMigrated(old *rom.Rom, r *rom.Rom) (bool, error) {
+	if haveFile(s.previous[rom.NormalizeTitle(old.Name)], r.Filename) {
+		return true, nil
+	}
+
+	files, err := ioutil.ReadDir(s.OutputDir())
+	if err != nil {
+		return false, err
+	}
+
+	for _, base := range []string{helpers.FileBase(r.Filename), r.ReleaseName()} {
+		for _, file := range files {
+			if !file.IsDir() && strings.HasPrefix(file.Name(), base+".") && rSave.MatchString(file.Name()[len(base):]) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// replacedRoms returns roms previously found in output directory for given game, that are not selected anymore. Patched copies written next to an original rom are ignored.
+func (s *System) replacedRoms(g *rom.Game) []*rom.Rom {
+	result := []*rom.Rom{}
+
+	candidates := s.previous[g.Key()]
+	if (len(candidates) == 0) && (len(g.Selected) > 0) {
+		candidates = s.previous[rom.NormalizeTitle(g.Selected[0].Name)]
+	}
+
+	for _, r := range candidates {
+		if haveFile(g.Selected, r.Filename) || isPatchedCopy(r, candidates) || isPatchedCopy(r, g.Selected) {
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// haveFile returns true if one of given roms has given file name
+func haveFile(roms []*rom.Rom, fileName string) bool {
+	for _, r := range roms {
+		if r.Filename == fileName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPatchedCopy returns true if given rom is a patched copy of one of given roms, eg: "Gunpey (Japan) [T-En].zip" for "Gunpey (Japan).zip"
+func isPatchedCopy(r *rom.Rom, roms []*rom.Rom) bool {
+	base := helpers.FileBase(r.Filename)
+
+	for _, other := range roms {
+		if other != r && strings.HasPrefix(base, helpers.FileBase(other.Filename)+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// renameSaves renames save files named after given old base name in output directory to given new base name, and returns the number of renamed files. Existing save files are never overwritten.
+func (s *System) renameSaves(oldBase string, newBase string) (int, error) {
+	if oldBase == newBase {
+		return 0, nil
+	}
+
+	files, err := ioutil.ReadDir(s.OutputDir())
+	if err != nil {
+		return 0, err
+	}
+
+	result := 0
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), oldBase+".") || !rSave.MatchString(file.Name()[len(oldBase):]) {
+			continue
+		}
+
+		oldPath := path.Join(s.OutputDir(), file.Name())
+		newPath := path.Join(s.OutputDir(), newBase+file.Name()[len(oldBase):])
+
+		if _, err := os.Stat(newPath); err == nil {
+			s.log(fmt.Sprintf("WARN: Save file '%s' not migrated, '%s' already exists\n", file.Name(), path.Base(newPath)))
+			continue
+		}
+
+		if s.Options.Debug {
+			s.log(fmt.Sprintf("Renaming save file '%s' to: %s\n", file.Name(), path.Base(newPath)))
+		}
+
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return result, err
+		}
+
+		result++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/rom"
+)
+
+func TestMigrateSaves(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	infos, _ := FindInfos("gb")
+
+	options := core.NewOptions()
+	options.Output = dir
+
+	run := func() *System {
+		s := New(infos, options)
+		os.MkdirAll(s.OutputDir(), 0777)
+
+		if err := s.scanPrevious(); err != nil {
+			t.Fatal(err)
+		}
+
+		r := rom.MustFill(path.Join(s.OutputDir(), "Gunpey (Japan) (Rev 1).gb"))
+
+		g := rom.NewGame()
+		g.AddRom(r)
+		g.Selected = []*rom.Rom{r}
+
+		if err := ioutil.WriteFile(r.File, []byte("GUNPEY REV 1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.migrateSaves(g); err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	// previously selected rom is kept in output directory
+	outputDir := path.Join(dir, infos.Dir)
+	os.MkdirAll(outputDir, 0777)
+
+	for _, name := range []string{"Gunpey (Japan).gb", "Gunpey (Japan).srm"} {
+		if err := ioutil.WriteFile(path.Join(outputDir, name), []byte("GUNPEY"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := run()
+
+	if (len(s.Migrated) != 1) || (s.Renamed["Gunpey (Japan).gb"] != "Gunpey (Japan) (Rev 1).gb") {
+		t.Errorf("Failed to migrate saves, got migrated %v and renamed %v", s.Migrated, s.Renamed)
+	}
+
+	if _, err := os.Stat(path.Join(outputDir, "Gunpey (Japan) (Rev 1).srm")); err != nil {
+		t.Errorf("Failed to rename save file: %v", err)
+	}
+
+	// next run finds the previous rom again, but saves were already migrated
+	s = run()
+
+	if (len(s.Migrated) != 0) || (len(s.Renamed) != 0) {
+		t.Errorf("Failed to skip already migrated saves, got migrated %v and renamed %v", s.Migrated, s.Renamed)
+	}
+}
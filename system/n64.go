@@ -0,0 +1,86 @@
+package system
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/romheader"
+)
+
+// n64System is the full name of the system which roms byte order can be converted
+const n64System = "Nintendo - Nintendo 64"
+
+// formatN64Rom converts given N64 rom file to the byte order set with the -n64-format option, and sets the rom file extension to match. Zipped roms are rewritten with the renamed rom inside. The CRC32 of a converted rom describes the converted data, and the CRC32 of the original dump is kept in DumpCRC.
+func (s *System) formatN64Rom(r *rom.Rom) error {
+	format := s.Options.N64Format
+	if (format == "") || (s.Infos.FullName() != n64System) || (strings.ToLower(filepath.Ext(r.File)) == ".7z") {
+		return nil
+	}
+
+	data, name, err := helpers.ReadRom(r.File, helpers.MaxRomSize+1)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > helpers.MaxRomSize {
+		s.log(fmt.Sprintf("WARN: N64 rom too big, not converted: %s\n", r.Filename))
+		return nil
+	}
+
+	order := romheader.N64ByteOrder(data)
+	if order == "" {
+		s.log(fmt.Sprintf("WARN: Unknown N64 byte order, not converted: %s\n", r.Filename))
+		return nil
+	}
+
+	newName := helpers.FileBase(name) + "." + format
+	if (order == format) && (newName == name) {
+		return nil
+	}
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Converting '%s' from %s to %s\n", r.Filename, order, format))
+	}
+
+	if order != format {
+		// keep the checksum of the original dump, media mirrors are indexed with it. A patched rom already holds it.
+		if r.DumpCRC == "" {
+			if r.DumpCRC, err = r.ComputeCRC(); err != nil {
+				return err
+			}
+		}
+
+		data = romheader.ConvertByteOrder(data, order, format)
+
+		// describe converted data, DAT hashes don't match it anymore
+		r.CRC = fmt.Sprintf("%08X", crc32.ChecksumIEEE(data))
+		r.Size = 0
+	}
+
+	if strings.ToLower(filepath.Ext(r.File)) == ".zip" {
+		return helpers.WriteZip(r.File, newName, data)
+	}
+
+	newFile := path.Join(path.Dir(r.File), newName)
+	if err := ioutil.WriteFile(newFile, data, 0666); err != nil {
+		return err
+	}
+
+	if newFile != r.File {
+		if err := os.Remove(r.File); err != nil {
+			return err
+		}
+	}
+
+	r.File = newFile
+	r.Filename = newName
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package system
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/romheader"
+)
+
+func TestFormatN64Rom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	infos, _ := FindInfos("n64")
+
+	options := core.NewOptions()
+	options.N64Format = romheader.N64ByteSwapped
+	options.Output = dir
+
+	s := New(infos, options)
+
+	data := []byte("\x80\x37\x12\x40SUPER MARIO 64 DUMP DATA")
+	dumpCRC := fmt.Sprintf("%08X", crc32.ChecksumIEEE(data))
+
+	filePath := path.Join(dir, "Super Mario 64 (USA).z64")
+	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := rom.MustFill(filePath)
+	if err := s.formatN64Rom(r); err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := ioutil.ReadFile(path.Join(dir, "Super Mario 64 (USA).v64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if romheader.N64ByteOrder(converted) != romheader.N64ByteSwapped {
+		t.Errorf("Failed to convert N64 rom, got byte order '%s'", romheader.N64ByteOrder(converted))
+	}
+
+	// checksums describe the converted file, and the original dump
+	if expected := fmt.Sprintf("%08X", crc32.ChecksumIEEE(converted)); r.CRC != expected {
+		t.Errorf("Failed to update CRC of converted N64 rom, got '%s' but expected '%s'", r.CRC, expected)
+	}
+
+	if r.DumpCRC != dumpCRC {
+		t.Errorf("Failed to keep dump CRC of converted N64 rom, got '%s' but expected '%s'", r.DumpCRC, dumpCRC)
+	}
+
+	// exported DAT describes the converted file, not the DAT entry of the dump
+	s.Dats = []*dat.Dat{{Games: []*dat.Game{
+		{Name: "Super Mario 64 (USA)", Roms: []*dat.Rom{{Name: "Super Mario 64 (USA).z64", Size: int64(len(data)), CRC: dumpCRC}}},
+	}}}
+
+	g := s.datGame("Super Mario 64 (USA)", r)
+	if (len(g.Roms) != 1) || (g.Roms[0].Name != r.Filename) || (g.Roms[0].CRC != r.CRC) || (g.Roms[0].Size != int64(len(converted))) {
+		t.Errorf("Failed to describe converted N64 rom in exported DAT, got %+v", g.Roms[0])
+	}
+}
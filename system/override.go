@@ -0,0 +1,71 @@
+package system
+
+import (
+	"fmt"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/overrides"
+	"github.com/aymerick/charette/rom"
+)
+
+// DirNames returns the names that identify systems with given output directory in overrides: the directory itself, then systems full names and names
+func DirNames(dir string) []string {
+	result := []string{dir}
+
+	for _, infos := range SupportedSystems {
+		if infos.Dir == dir {
+			result = append(result, infos.FullName(), infos.Name)
+		}
+	}
+
+	return result
+}
+
+// OverrideSkip returns true if given rom must be skiped with given options and given override, with an explanation message. A forced game or a pinned rom is never skipped.
+func OverrideSkip(r *rom.Rom, options *core.Options, o *overrides.Override) (bool, string) {
+	if o == nil {
+		return SkipRom(r, options)
+	}
+
+	if o.Exclude {
+		o.Apply("excluded")
+		return true, "Excluded by override"
+	}
+
+	skip, msg := SkipRom(r, options)
+	if skip && (o.Force || o.Pinned(r.Filename)) {
+		o.Apply(fmt.Sprintf("forced '%s' (%s)", r.Filename, msg))
+		return false, ""
+	}
+
+	return skip, msg
+}
+
+// OverrideBestRoms returns the best roms of given game with given options, or the pinned rom with all its parts if given override pins one of the game roms
+func OverrideBestRoms(g *rom.Game, options *core.Options, o *overrides.Override) []*rom.Rom {
+	rk := rom.RankingFor(options)
+
+	if (o == nil) || (o.Pin == "") {
+		return g.RankedBestRoms(rk)
+	}
+
+	for _, r := range g.Roms {
+		if !o.Pinned(r.Filename) {
+			continue
+		}
+
+		// keep all parts of pinned release, the pinned rom wins for its own part
+		release := rom.NewGame()
+		for _, part := range g.Roms {
+			if (part.ReleaseTitle() == r.ReleaseTitle()) && ((part == r) || (part.Part != r.Part)) {
+				release.AddRom(part)
+			}
+		}
+
+		o.Apply(fmt.Sprintf("pinned '%s'", r.Filename))
+
+		return release.RankedBestRoms(rk)
+	}
+
+	return g.RankedBestRoms(rk)
+}
@@ -0,0 +1,246 @@
+package system
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/patch"
+	"github.com/aymerick/charette/rom"
+)
+
+// findPatch sets the patch of given rom, if one is found in patches directory. A patch that expects another source checksum is not set, so that rom is skipped and ranked as an unpatched one.
+func (s *System) findPatch(r *rom.Rom) {
+	found := s.Patches.Find(helpers.FileBase(r.Filename), func() string {
+		return s.patchKeyCRC(r)
+	})
+
+	files := []string{}
+
+	for _, file := range found {
+		match, err := s.patchMatches(file, r)
+		if err != nil {
+			s.log(fmt.Sprintf("ERR: Failed to check patch '%s' for '%s': %v\n", path.Base(file), r.Filename, err))
+			continue
+		}
+
+		if !match {
+			msg := fmt.Sprintf("%s: source checksum mismatch with '%s'", path.Base(file), r.Filename)
+			if !stringIn(s.Unpaired, msg) {
+				s.Unpaired = append(s.Unpaired, msg)
+			}
+
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	if len(files) > 0 {
+		r.Patch = files[0]
+
+		if len(files) > 1 {
+			s.log(fmt.Sprintf("WARN: Several patches found for '%s', using: %s\n", r.Filename, path.Base(r.Patch)))
+		}
+	}
+}
+
+// patchKeyCRC returns the CRC32 of given rom to look up patches keyed by CRC32. The CRC32 found in system DATs for that rom name is used when available, so that rom data is only read for roms unknown to DATs.
+func (s *System) patchKeyCRC(r *rom.Rom) string {
+	for _, d := range s.Dats {
+		if g := d.FindGame(helpers.FileBase(r.Filename)); (g != nil) && (len(g.Roms) == 1) && (g.Roms[0].CRC != "") {
+			return strings.ToUpper(g.Roms[0].CRC)
+		}
+	}
+
+	crc, err := r.ComputeCRC()
+	if err != nil {
+		s.log(fmt.Sprintf("ERR: Failed to compute CRC of '%s': %v\n", r.Filename, err))
+	}
+
+	return crc
+}
+
+// patchMatches returns true if the source checksum expected by given patch file is unknown, or matches given rom data or its headerless data
+func (s *System) patchMatches(file string, r *rom.Rom) (bool, error) {
+	p, err := patch.Load(file)
+	if err != nil {
+		return false, err
+	}
+
+	crc, ok := p.SourceCRC()
+	if !ok {
+		return true, nil
+	}
+
+	data, _, err := helpers.ReadRom(r.File, helpers.MaxRomSize+1)
+	if err != nil {
+		return false, err
+	}
+
+	if len(data) > helpers.MaxRomSize {
+		return false, fmt.Errorf("Rom too big to be patched")
+	}
+
+	_, body := s.Skipper.Split(data)
+
+	return (crc32.ChecksumIEEE(data) == crc) || (crc32.ChecksumIEEE(body) == crc), nil
+}
+
+// applyPatch applies the patch of given moved rom, and writes the patched rom next to the original one or instead of it. Returns the patched rom when it is written next to the original one. The CRC32 of the original dump is kept in DumpCRC of the patched rom. In replace mode, a rom that fails to be patched is deleted and false is returned, so that it is not shipped unpatched.
+func (s *System) applyPatch(r *rom.Rom) (*rom.Rom, bool, error) {
+	if (r.Patch == "") || (strings.ToLower(filepath.Ext(r.File)) == ".7z") {
+		return nil, true, nil
+	}
+
+	if s.Options.PatchMode == core.PatchSoftpatch {
+		return nil, true, s.placePatch(r)
+	}
+
+	p, err := patch.Load(r.Patch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	source, name, err := helpers.ReadRom(r.File, helpers.MaxRomSize+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var data []byte
+	if len(source) > helpers.MaxRomSize {
+		err = fmt.Errorf("Rom too big to be patched")
+	} else {
+		data, err = s.patchData(p, source)
+	}
+
+	if err != nil {
+		if s.Options.PatchMode == core.PatchReplace {
+			s.log(fmt.Sprintf("WARN: Failed to apply patch '%s' to '%s', rom dropped: %v\n", path.Base(r.Patch), r.Filename, err))
+			return nil, false, os.Remove(r.File)
+		}
+
+		s.log(fmt.Sprintf("WARN: Failed to apply patch '%s' to '%s': %v\n", path.Base(r.Patch), r.Filename, err))
+		return nil, true, nil
+	}
+
+	result := r
+	if s.Options.PatchMode == core.PatchBeside {
+		label := patch.Label(r.Patch, helpers.FileBase(r.Filename))
+		if label == "" {
+			label = " (Patched)"
+		}
+
+		result = rom.New(path.Join(path.Dir(r.File), helpers.FileBase(r.Filename)+label+path.Ext(r.Filename)))
+		if err := result.Fill(); err != nil {
+			return nil, false, err
+		}
+
+		result.Skipper = r.Skipper
+		name = helpers.FileBase(name) + label + path.Ext(name)
+	}
+
+	// media and DAT entries are found with the checksum of the original dump
+	dumpCRC := r.DumpCRC
+	if dumpCRC == "" {
+		if dumpCRC, err = r.ComputeCRC(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Patching '%s' with: %s\n", r.Filename, path.Base(r.Patch)))
+	}
+
+	if strings.ToLower(filepath.Ext(result.File)) == ".zip" {
+		err = helpers.WriteZip(result.File, name, data)
+	} else {
+		err = ioutil.WriteFile(result.File, data, 0666)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	result.CRC = ""
+	result.DumpCRC = dumpCRC
+	s.Patched = append(s.Patched, fmt.Sprintf("%s: %s", result.Filename, path.Base(r.Patch)))
+
+	if result == r {
+		return nil, true, nil
+	}
+
+	return result, true, nil
+}
+
+// placePatch copies the patch of given moved rom next to it, renamed after it, so that frontends apply it at load time
+func (s *System) placePatch(r *rom.Rom) error {
+	outputPath := path.Join(s.OutputDir(), helpers.FileBase(r.Filename)+path.Ext(r.Patch))
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Copying patch '%s' to: %s\n", path.Base(r.Patch), outputPath))
+	}
+
+	if err := helpers.CopyFile(r.Patch, outputPath); err != nil {
+		return err
+	}
+
+	s.Patched = append(s.Patched, fmt.Sprintf("%s: %s", path.Base(outputPath), path.Base(r.Patch)))
+
+	return nil
+}
+
+// patchedLabel returns the label of given patched copy of a selected rom of given game, eg: "[T-En]" for "Gunpey (Japan) [T-En].zip" written next to "Gunpey (Japan).zip", or an empty string if given rom is not a patched copy
+func patchedLabel(g *rom.Game, r *rom.Rom) string {
+	if !containsRom(g.Patched, r) {
+		return ""
+	}
+
+	for _, selected := range g.Selected {
+		if base := helpers.FileBase(selected.Filename); strings.HasPrefix(helpers.FileBase(r.Filename), base+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(helpers.FileBase(r.Filename), base))
+		}
+	}
+
+	return ""
+}
+
+// checkUnpaired records patches of given game roms that were not selected, when no selected rom has a patch, eg: a patch that targets another revision
+func (s *System) checkUnpaired(g *rom.Game) {
+	for _, r := range g.Selected {
+		if r.Patch != "" {
+			return
+		}
+	}
+
+	for _, r := range g.Roms {
+		if (r.Patch != "") && (len(g.Selected) > 0) && !containsRom(g.Selected, r) {
+			s.Unpaired = append(s.Unpaired, fmt.Sprintf("%s: targets '%s', but '%s' is selected", path.Base(r.Patch), r.Filename, g.Selected[0].Filename))
+		}
+	}
+}
+
+// patchData applies given patch to given rom data. When the patch does not apply to the whole rom, it is applied to headerless data and the header is kept.
+func (s *System) patchData(p *patch.Patch, data []byte) ([]byte, error) {
+	result, err := p.Apply(data)
+	if err == nil {
+		return result, nil
+	}
+
+	header, body := s.Skipper.Split(data)
+	if len(header) == 0 {
+		return nil, err
+	}
+
+	if result, errBody := p.Apply(body); errBody == nil {
+		return append(append([]byte{}, header...), result...), nil
+	}
+
+	return nil, err
+}
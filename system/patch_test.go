@@ -0,0 +1,305 @@
+package system
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/gamelist"
+	"github.com/aymerick/charette/patch"
+	"github.com/aymerick/charette/rom"
+)
+
+// bpsPatch returns a BPS patch that writes given target data, and that expects given source checksum
+func bpsPatch(source []byte, sourceCRC uint32, target []byte) []byte {
+	number := func(n int) []byte {
+		result := []byte{}
+		for {
+			x := byte(n & 0x7f)
+			n >>= 7
+			if n == 0 {
+				return append(result, 0x80|x)
+			}
+
+			result = append(result, x)
+			n--
+		}
+	}
+
+	data := []byte("BPS1")
+	data = append(data, number(len(source))...)
+	data = append(data, number(len(target))...)
+	data = append(data, number(0)...)
+	data = append(data, number((len(target)-1)<<2|1)...)
+	data = append(data, target...)
+
+	footer := make([]byte, 4)
+
+	binary.LittleEndian.PutUint32(footer, sourceCRC)
+	data = append(data, footer...)
+
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(target))
+	data = append(data, footer...)
+
+	binary.LittleEndian.PutUint32(footer, crc32.ChecksumIEEE(data))
+
+	return append(data, footer...)
+}
+
+// newPatchSystem returns a Game Boy system with given patch mode, and a patches library in given directory
+func newPatchSystem(t *testing.T, dir string, mode string) *System {
+	infos, _ := FindInfos("gb")
+
+	options := core.NewOptions()
+	options.Regions = []string{"Japan"}
+	options.PatchMode = mode
+	options.Output = path.Join(dir, "roms")
+
+	library, err := patch.NewLibrary(path.Join(dir, "patches"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := New(infos, options)
+	result.Patches = library
+
+	return result
+}
+
+func TestFindPatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := []byte("GUNPEY")
+	other := []byte("GUNPEY REV 1")
+
+	files := map[string][]byte{
+		"Gunpey (Japan).gb":                         source,
+		"Gunpey (Japan) (Rev 1).gb":                 other,
+		"patches/Gunpey (Japan) [T-En].bps":         bpsPatch(source, crc32.ChecksumIEEE(source), []byte("GUNPEY EN")),
+		"patches/Gunpey (Japan) (Rev 1) [T-En].bps": bpsPatch(source, crc32.ChecksumIEEE(source), []byte("GUNPEY EN")),
+	}
+
+	for name, data := range files {
+		os.MkdirAll(path.Dir(path.Join(dir, name)), 0777)
+
+		if err := ioutil.WriteFile(path.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		fileName string
+		patch    string
+		unpaired string
+	}{
+		{"Gunpey (Japan).gb", "Gunpey (Japan) [T-En].bps", ""},
+		{"Gunpey (Japan) (Rev 1).gb", "", "Gunpey (Japan) (Rev 1) [T-En].bps: source checksum mismatch with 'Gunpey (Japan) (Rev 1).gb'"},
+	}
+
+	for _, test := range tests {
+		s := newPatchSystem(t, dir, core.PatchBeside)
+
+		r := rom.New(path.Join(dir, test.fileName))
+		if err := r.Fill(); err != nil {
+			t.Fatal(err)
+		}
+
+		s.findPatch(r)
+
+		if path.Base(r.Patch) != path.Base(test.patch) {
+			t.Errorf("Failed to find patch of '%s', got '%s' but expected '%s'", test.fileName, r.Patch, test.patch)
+		}
+
+		if strings.Join(s.Unpaired, "\n") != test.unpaired {
+			t.Errorf("Failed to report unpaired patch of '%s', got %v but expected '%s'", test.fileName, s.Unpaired, test.unpaired)
+		}
+
+		if skip, _ := SkipRom(r, &core.Options{Strict: true, Regions: []string{"USA"}}); skip == (test.patch != "") {
+			t.Errorf("Strict mode failed for '%s' with patch '%s'", test.fileName, r.Patch)
+		}
+	}
+}
+
+func TestPlacePatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []byte("PATCHEOF")
+
+	os.MkdirAll(path.Join(dir, "patches"), 0777)
+	if err := ioutil.WriteFile(path.Join(dir, "patches", "Gunpey (Japan) [T-En].ips"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newPatchSystem(t, dir, core.PatchSoftpatch)
+	os.MkdirAll(s.OutputDir(), 0777)
+
+	r := rom.New(path.Join(s.OutputDir(), "Gunpey (Japan).zip"))
+	r.Patch = path.Join(dir, "patches", "Gunpey (Japan) [T-En].ips")
+
+	if err := s.placePatch(r); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ioutil.ReadFile(path.Join(s.OutputDir(), "Gunpey (Japan).ips"))
+	if (err != nil) || (string(result) != string(data)) {
+		t.Errorf("Failed to place patch, got '%s' with error: %v", result, err)
+	}
+
+	if strings.Join(s.Patched, "\n") != "Gunpey (Japan).ips: Gunpey (Japan) [T-En].ips" {
+		t.Errorf("Failed to report placed patch, got %v", s.Patched)
+	}
+}
+
+func TestCheckUnpaired(t *testing.T) {
+	tests := []struct {
+		mode     string
+		regions  []string
+		unpaired string
+	}{
+		// the patched revision wins when patches are applied
+		{core.PatchBeside, []string{"Japan"}, ""},
+		{core.PatchReplace, []string{"Japan"}, ""},
+
+		// frontends apply softpatches, so the latest revision is selected
+		{core.PatchSoftpatch, []string{"Japan"}, "Gunpey (Japan) [T-En].ips: targets 'Gunpey (Japan).zip', but 'Gunpey (Japan) (Rev 1).zip' is selected"},
+
+		// another region is preferred
+		{core.PatchBeside, []string{"USA", "Japan"}, "Gunpey (Japan) [T-En].ips: targets 'Gunpey (Japan).zip', but 'Gunpey (USA).zip' is selected"},
+	}
+
+	for _, test := range tests {
+		options := core.NewOptions()
+		options.Regions = test.regions
+		options.PatchMode = test.mode
+
+		s := New(Infos{}, options)
+
+		g := rom.NewGame()
+		g.AddRom(rom.MustFill("Gunpey (Japan) (Rev 1).zip"))
+		g.AddRom(rom.MustFill("Gunpey (Japan).zip")).Patch = "Gunpey (Japan) [T-En].ips"
+		g.AddRom(rom.MustFill("Gunpey (USA).zip"))
+
+		g.Selected = s.bestRoms(g)
+		s.checkUnpaired(g)
+
+		if strings.Join(s.Unpaired, "\n") != test.unpaired {
+			t.Errorf("Failed to report unpaired patches in %s mode with regions %v, got %v but expected '%s'", test.mode, test.regions, s.Unpaired, test.unpaired)
+		}
+	}
+}
+
+func TestPatchKeyCRC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(path.Join(dir, "patches"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newPatchSystem(t, dir, core.PatchBeside)
+	s.Dats = []*dat.Dat{{Games: []*dat.Game{
+		{Name: "Gunpey (Japan)", Roms: []*dat.Rom{{Name: "Gunpey (Japan).gb", CRC: "1a2b3c4d"}}},
+	}}}
+
+	// rom data is not read when DATs know that rom
+	r := rom.New(path.Join(dir, "Gunpey (Japan).gb"))
+	if crc := s.patchKeyCRC(r); crc != "1A2B3C4D" {
+		t.Errorf("Failed to get patch key CRC from DATs, got '%s' but expected '1A2B3C4D'", crc)
+	}
+
+	data := []byte("GUNPEY REV 1")
+	if err := ioutil.WriteFile(path.Join(dir, "Gunpey (Japan) (Rev 1).gb"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r = rom.New(path.Join(dir, "Gunpey (Japan) (Rev 1).gb"))
+	if crc, expected := s.patchKeyCRC(r), fmt.Sprintf("%08X", crc32.ChecksumIEEE(data)); crc != expected {
+		t.Errorf("Failed to compute patch key CRC, got '%s' but expected '%s'", crc, expected)
+	}
+}
+
+func TestMoveGameRomsPatched(t *testing.T) {
+	dir, err := ioutil.TempDir("", "charette-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := []byte("GUNPEY")
+	target := []byte("GUNPEY EN")
+
+	files := map[string][]byte{
+		"input/Gunpey (Japan).gb":           source,
+		"patches/Gunpey (Japan) [T-En].bps": bpsPatch(source, crc32.ChecksumIEEE(source), target),
+	}
+
+	for name, data := range files {
+		os.MkdirAll(path.Dir(path.Join(dir, name)), 0777)
+
+		if err := ioutil.WriteFile(path.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newPatchSystem(t, dir, core.PatchBeside)
+	os.MkdirAll(s.OutputDir(), 0777)
+
+	r := rom.MustFill(path.Join(dir, "input", "Gunpey (Japan).gb"))
+	s.findPatch(r)
+
+	g := rom.NewGame()
+	g.AddRom(r)
+	g.Selected = []*rom.Rom{r}
+	s.Games[g.Key()] = g
+
+	if err := s.moveGameRoms(g); err != nil {
+		t.Fatal(err)
+	}
+
+	// patched copy is tracked next to the selected rom
+	if (len(g.Selected) != 1) || (len(g.Patched) != 1) || (g.Patched[0].Filename != "Gunpey (Japan) [T-En].gb") {
+		t.Fatalf("Failed to track patched copy, got selected %v and patched %v", g.Selected, g.Patched)
+	}
+
+	if expected := fmt.Sprintf("%08X", crc32.ChecksumIEEE(source)); g.Patched[0].DumpCRC != expected {
+		t.Errorf("Failed to keep dump CRC of patched copy, got '%s' but expected '%s'", g.Patched[0].DumpCRC, expected)
+	}
+
+	if size := GameSize(g); size != int64(len(source)+len(target)) {
+		t.Errorf("Failed to count patched copy in game size, got %d but expected %d", size, len(source)+len(target))
+	}
+
+	gl := gamelist.New()
+	s.FillGamelist(gl)
+
+	if entry := gl.Find("Gunpey (Japan) [T-En].gb"); (entry == nil) || (entry.Name != "Gunpey [T-En]") {
+		t.Errorf("Failed to add gamelist entry of patched copy, got %+v", entry)
+	}
+
+	d := s.ExportDat()
+	if len(d.Games) != 2 {
+		t.Fatalf("Failed to export patched copy, got %d DAT games but expected 2", len(d.Games))
+	}
+
+	if p := d.Games[1].Roms[0]; (p.Name != "Gunpey (Japan) [T-En].gb") || (p.CRC != fmt.Sprintf("%08X", crc32.ChecksumIEEE(target))) || (p.Size != int64(len(target))) {
+		t.Errorf("Failed to describe patched copy in exported DAT, got %+v", p)
+	}
+}
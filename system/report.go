@@ -0,0 +1,194 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/rom"
+)
+
+// games statuses in completeness report
+const (
+	// a rom in preferred regions is selected
+	StatusHave = "have"
+
+	// only a rom in non-preferred regions is selected
+	StatusFallback = "fallback"
+
+	// no rom is selected
+	StatusMiss = "miss"
+)
+
+// ReasonMissing is the explanation message of a game found in system DATs, but not in archives
+const ReasonMissing = "Missing from input archives"
+
+// Skip represents a rom skipped while processing archives
+type Skip struct {
+	// game name, eg: "Legend of Zelda, The - Link's Awakening"
+	Name string
+
+	// rom file name
+	Filename string
+
+	// explanation message
+	Reason string
+}
+
+// ReportEntry represents the completeness status of a game
+type ReportEntry struct {
+	// game name
+	Name string
+
+	// StatusHave, StatusFallback or StatusMiss
+	Status string
+
+	// selected rom file name, empty if none
+	Rom string
+
+	// explanation message, empty for a game with a rom in preferred regions
+	Reason string
+
+	// CRC32 of selected rom, from system DATs or computed, empty if unknown
+	CRC string
+
+	// file names of patched copies written next to selected rom, comma separated, empty if none
+	Patched string
+}
+
+// addSkip records a rom skipped while processing archives
+func (s *System) addSkip(r *rom.Rom, reason string) {
+	key := rom.NormalizeTitle(r.Name)
+
+	for _, skip := range s.Skips[key] {
+		if skip.Filename == r.Filename {
+			// older copy of the same rom
+			return
+		}
+	}
+
+	s.Skips[key] = append(s.Skips[key], &Skip{Name: r.Name, Filename: r.Filename, Reason: reason})
+}
+
+// Report returns the completeness status of all games found in archives, and of games from system DATs that are missing from archives, sorted by name
+func (s *System) Report() []*ReportEntry {
+	s.PlanRoms()
+
+	result := []*ReportEntry{}
+	done := map[string]bool{}
+
+	rk := rom.RankingFor(s.Options)
+
+	leftOut := map[*rom.Game]bool{}
+	for _, g := range s.LeftOut {
+		leftOut[g] = true
+	}
+
+	for key, g := range s.Games {
+		done[key] = true
+		entry := &ReportEntry{Name: g.Name, Status: StatusMiss}
+
+		switch {
+		case len(g.Selected) > 0:
+			r := g.Selected[0]
+			entry.Rom = r.Filename
+			entry.CRC = r.CRC
+
+			if d := s.findDatGame(helpers.FileBase(r.Filename), r); (d != nil) && (len(d.Roms) > 0) {
+				entry.CRC = d.Roms[0].CRC
+			}
+
+			patched := []string{}
+			for _, p := range g.Patched {
+				patched = append(patched, p.Filename)
+			}
+
+			entry.Patched = strings.Join(patched, ", ")
+
+			if rk.HaveRegion(r) {
+				entry.Status = StatusHave
+			} else {
+				entry.Status = StatusFallback
+				entry.Reason = fmt.Sprintf("Only non-preferred region available: %s", strings.Join(r.Regions, ", "))
+			}
+
+		case s.filtered[key] != "":
+			entry.Reason = s.filtered[key]
+
+		case leftOut[g]:
+			entry.Reason = "Left out by storage budget"
+
+		default:
+			entry.Reason = "No acceptable rom"
+		}
+
+		result = append(result, entry)
+	}
+
+	for key, entry := range s.duplicates {
+		if !done[key] {
+			done[key] = true
+			result = append(result, entry)
+		}
+	}
+
+	for key, skips := range s.Skips {
+		if done[key] {
+			continue
+		}
+
+		done[key] = true
+
+		reasons := []string{}
+		for _, skip := range skips {
+			if !stringIn(reasons, skip.Reason) {
+				reasons = append(reasons, skip.Reason)
+			}
+		}
+
+		result = append(result, &ReportEntry{Name: skips[0].Name, Status: StatusMiss, Reason: strings.Join(reasons, ", ")})
+	}
+
+	for _, d := range s.Dats {
+		for _, g := range d.Games {
+			name := datGameTitle(g.Name)
+
+			if key := rom.NormalizeTitle(name); !done[key] {
+				done[key] = true
+				result = append(result, &ReportEntry{Name: name, Status: StatusMiss, Reason: ReasonMissing})
+			}
+		}
+	}
+
+	sort.Sort(entriesByName(result))
+
+	return result
+}
+
+// datGameTitle returns given DAT game name without its tags, eg: "Tetris" for "Tetris (World) (Rev A)"
+func datGameTitle(name string) string {
+	if i := strings.Index(name, " ("); i > 0 {
+		return name[:i]
+	}
+
+	return name
+}
+
+// entriesByName sorts report entries by game name
+type entriesByName []*ReportEntry
+
+// Implements sort.Interface
+func (a entriesByName) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a entriesByName) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a entriesByName) Less(i, j int) bool {
+	return a[i].Name < a[j].Name
+}
@@ -0,0 +1,87 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/curated"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/rom"
+)
+
+func TestReport(t *testing.T) {
+	options := core.NewOptions()
+	options.Regions = []string{"Europe", "USA"}
+
+	s := New(Infos{}, options)
+	s.Exclude = curated.Parse([]byte("Alleyway\n"))
+
+	addGame := func(name string, fileNames ...string) *rom.Game {
+		g := rom.NewGame()
+		g.Name = name
+
+		for _, fileName := range fileNames {
+			g.AddRom(rom.MustFill(fileName))
+		}
+
+		s.Games[rom.NormalizeTitle(name)] = g
+
+		return g
+	}
+
+	addGame("Tetris", "Tetris (Europe).gb", "Tetris (Japan).gb")
+	kwirk := addGame("Kwirk", "Kwirk (Japan).gb")
+	addGame("Alleyway", "Alleyway (World).gb")
+	addGame("Mystery")
+	golf := addGame("Golf", "Golf (USA, Europe).gb")
+
+	// skipped roms of a game without candidates
+	s.addSkip(rom.MustFill("Pocket Monsters - Aka (Japan).gb"), "Strict: [Japan]")
+	s.addSkip(rom.MustFill("Pocket Monsters - Aka (Japan) (Beta).gb"), "Ignore beta")
+	s.addSkip(rom.MustFill("Pocket Monsters - Aka (Japan) (Beta).gb"), "Ignore beta")
+
+	// skipped roms of a game with candidates are not listed
+	s.addSkip(rom.MustFill("Tetris (Japan) (Beta).gb"), "Ignore beta")
+
+	// game found in a preferred system
+	s.duplicates[rom.NormalizeTitle("Baseball")] = &ReportEntry{Name: "Baseball", Status: StatusHave, Reason: "Selected from Nintendo - Game Boy Color"}
+
+	s.Dats = []*dat.Dat{{Games: []*dat.Game{
+		{Name: "Tetris (Europe)", Roms: []*dat.Rom{{Name: "Tetris (Europe).gb", CRC: "46DF91AD"}}},
+		{Name: "Kwirk (Japan)", Roms: []*dat.Rom{{Name: "Kwirk (Japan).gb", CRC: "12345678"}}},
+		{Name: "Dr. Mario (World) (Rev 1)", Roms: []*dat.Rom{{Name: "Dr. Mario (World) (Rev 1).gb", CRC: "AABBCCDD"}}},
+		{Name: "Pocket Monsters - Aka (Japan)", Roms: []*dat.Rom{{Name: "Pocket Monsters - Aka (Japan).gb", CRC: "13652705"}}},
+	}}}
+
+	s.PlanRoms()
+	s.LeaveOut(golf)
+
+	// patched copy written next to selected rom
+	kwirk.Patched = []*rom.Rom{rom.MustFill("Kwirk (Japan) [T-En].gb")}
+
+	expected := []ReportEntry{
+		{"Alleyway", StatusMiss, "", "In exclude list", "", ""},
+		{"Baseball", StatusHave, "", "Selected from Nintendo - Game Boy Color", "", ""},
+		{"Dr. Mario", StatusMiss, "", ReasonMissing, "", ""},
+		{"Golf", StatusMiss, "", "Left out by storage budget", "", ""},
+		{"Kwirk", StatusFallback, "Kwirk (Japan).gb", "Only non-preferred region available: Japan", "12345678", "Kwirk (Japan) [T-En].gb"},
+		{"Mystery", StatusMiss, "", "No acceptable rom", "", ""},
+		{"Pocket Monsters - Aka", StatusMiss, "", "Strict: [Japan], Ignore beta", "", ""},
+		{"Tetris", StatusHave, "Tetris (Europe).gb", "", "46DF91AD", ""},
+	}
+
+	result := s.Report()
+	if len(result) != len(expected) {
+		for _, entry := range result {
+			t.Logf("%+v", entry)
+		}
+
+		t.Fatalf("Report failed, got %d entries but expected %d", len(result), len(expected))
+	}
+
+	for i, entry := range result {
+		if *entry != expected[i] {
+			t.Errorf("Report failed\n\tgot     : %+v\n\texpected: %+v", *entry, expected[i])
+		}
+	}
+}
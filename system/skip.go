@@ -0,0 +1,45 @@
+package system
+
+import (
+	"fmt"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/rom"
+)
+
+// SkipRom returns true if given rom must be skiped with given options, with an explanation message. A rom with a patch, eg: a fan translation, is not skipped in strict mode.
+func SkipRom(r *rom.Rom, options *core.Options) (bool, string) {
+	if options.Strict && (r.Patch == "") && !rom.RankingFor(options).HaveRegion(r) {
+		return true, fmt.Sprintf("Strict: %v", r.Regions)
+	}
+
+	if r.Proto && !options.KeepProto {
+		return true, "Ignore proto"
+	}
+
+	if r.Beta && !options.KeepBeta {
+		return true, "Ignore beta"
+	}
+
+	if r.Bios {
+		return true, "Ignore bios"
+	}
+
+	if r.Sample && !options.KeepSample {
+		return true, "Ignore sample"
+	}
+
+	if r.Demo && !options.KeepDemo {
+		return true, "Ignore demo"
+	}
+
+	if r.Pirate && !options.KeepPirate {
+		return true, "Ignore pirate"
+	}
+
+	if r.Promo && !options.KeepPromo {
+		return true, "Ignore promo"
+	}
+
+	return false, ""
+}
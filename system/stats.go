@@ -0,0 +1,139 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats represents the processing statistics of a system
+type Stats struct {
+	// system full name
+	System string
+
+	// number of roms found in archives
+	Seen int
+
+	// number of games with candidate roms, including games found in a preferred system sharing the same output directory
+	Grouped int
+
+	// number of selected games
+	Selected int
+
+	// skipped roms, by skip reason, sorted by count
+	Skipped []Count
+
+	// selected games, by best preferred region, sorted by count
+	Regions []Count
+
+	// size of processed archives
+	BytesIn int64
+
+	// size of selected roms
+	BytesOut int64
+
+	// time spent extracting and processing archives
+	Extracting time.Duration
+
+	// time spent selecting and moving roms
+	Selecting time.Duration
+}
+
+// Count represents a named counter
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Stats returns the processing statistics of that system
+func (s *System) Stats() *Stats {
+	result := &Stats{
+		System:     s.Infos.FullName(),
+		Seen:       s.Seen,
+		Grouped:    len(s.Games) + s.Duplicates,
+		Selected:   s.SelectedGames(),
+		BytesIn:    s.BytesIn,
+		BytesOut:   s.BytesOut,
+		Extracting: s.Extracting,
+		Selecting:  s.Selecting,
+	}
+
+	reasons := map[string]int{}
+	for _, skips := range s.Skips {
+		for _, skip := range skips {
+			reasons[SkipCategory(skip.Reason)]++
+		}
+	}
+
+	regions := map[string]int{}
+	for _, g := range s.Games {
+		if len(g.Selected) > 0 {
+			regions[g.Selected[0].BestRegion(s.Options.Regions)]++
+		}
+	}
+
+	result.Skipped = SortedCounts(reasons)
+	result.Regions = SortedCounts(regions)
+
+	return result
+}
+
+// SkipCategory returns the category of given skip reason, eg: "Strict" for "Strict: [Japan]"
+func SkipCategory(reason string) string {
+	if i := strings.Index(reason, ":"); i > 0 {
+		return reason[:i]
+	}
+
+	return reason
+}
+
+// SortedCounts returns given counters, sorted by count then by name
+func SortedCounts(counts map[string]int) []Count {
+	result := []Count{}
+	for name, count := range counts {
+		result = append(result, Count{name, count})
+	}
+
+	sort.Sort(countsByCount(result))
+
+	return result
+}
+
+// pathSize returns the size of given file, or the total size of files in given directory
+func pathSize(filePath string) int64 {
+	var result int64
+
+	filepath.Walk(filePath, func(p string, info os.FileInfo, err error) error {
+		if (err == nil) && !info.IsDir() {
+			result += info.Size()
+		}
+
+		return nil
+	})
+
+	return result
+}
+
+// countsByCount sorts counters by decreasing count, then by name
+type countsByCount []Count
+
+// Implements sort.Interface
+func (a countsByCount) Len() int {
+	return len(a)
+}
+
+// Implements sort.Interface
+func (a countsByCount) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Implements sort.Interface
+func (a countsByCount) Less(i, j int) bool {
+	if a[i].Count != a[j].Count {
+		return a[i].Count > a[j].Count
+	}
+
+	return a[i].Name < a[j].Name
+}
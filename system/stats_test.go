@@ -0,0 +1,46 @@
+package system
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortedCounts(t *testing.T) {
+	tests := []struct {
+		counts   map[string]int
+		expected []Count
+	}{
+		{map[string]int{}, []Count{}},
+		{map[string]int{"USA": 3, "Europe": 12, "Japan": 7}, []Count{{"Europe", 12}, {"Japan", 7}, {"USA", 3}}},
+		{map[string]int{"Strict": 2, "Ignore demo": 2, "Ignore beta": 2, "Ignore proto": 5}, []Count{{"Ignore proto", 5}, {"Ignore beta", 2}, {"Ignore demo", 2}, {"Strict", 2}}},
+	}
+
+	for _, test := range tests {
+		// map iteration order is random, so each case is checked several times
+		for i := 0; i < 10; i++ {
+			if result := SortedCounts(test.counts); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Failed to sort counts %v, got %v but expected %v", test.counts, result, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestSkipCategory(t *testing.T) {
+	tests := []struct {
+		reason   string
+		expected string
+	}{
+		{"Strict: [Japan]", "Strict"},
+		{"Strict: [Japan Korea]", "Strict"},
+		{"Ignore beta", "Ignore beta"},
+		{"Excluded by override", "Excluded by override"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if result := SkipCategory(test.reason); result != test.expected {
+			t.Errorf("Failed to get category of skip reason '%s', got '%s' but expected '%s'", test.reason, result, test.expected)
+		}
+	}
+}
@@ -1,11 +1,29 @@
 package system
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/curated"
+	"github.com/aymerick/charette/dat"
+	"github.com/aymerick/charette/gamelist"
+	"github.com/aymerick/charette/helpers"
+	"github.com/aymerick/charette/media"
+	"github.com/aymerick/charette/overrides"
+	"github.com/aymerick/charette/patch"
 	"github.com/aymerick/charette/rom"
+	"github.com/aymerick/charette/skipper"
+)
+
+const (
+	// mediaDir is the media directory name in system output directory
+	mediaDir = "media"
 )
 
 // System represents a gaming system found in no-intro archives
@@ -16,7 +34,7 @@ type System struct {
 	// options
 	Options *core.Options
 
-	// all selected games from all archives
+	// all candidate games from all archives, indexed by normalized name
 	Games map[string]*rom.Game
 
 	// total number of processed files from all archives
@@ -25,18 +43,206 @@ type System struct {
 	// total number of skipped files from all archives
 	Skipped int
 
-	// selected regions stats from all archives
-	RegionsStats map[string]int
+	// total number of roms found in all archives
+	Seen int
+
+	// total size of processed archives
+	BytesIn int64
+
+	// total size of selected roms
+	BytesOut int64
+
+	// time spent extracting and processing archives
+	Extracting time.Duration
+
+	// time spent selecting and moving roms
+	Selecting time.Duration
+
+	// installed media files paths relative to output directory, indexed by rom file name then by media kind
+	Media map[string]map[string]string
+
+	// selected games without media
+	MissingMedia []*rom.Game
+
+	// number of games skipped because they were found in a preferred system sharing the same output directory
+	Duplicates int
+
+	// systems sharing the same output directory
+	Group *Group
+
+	// manual overrides, nil if none
+	Overrides *overrides.List
+
+	// curated lists of games to include and to exclude, nil if none
+	Include *curated.List
+	Exclude *curated.List
+
+	// header skipper, nil if none
+	Skipper *skipper.Detector
+
+	// system DATs
+	Dats []*dat.Dat
+
+	// patches library, nil if none
+	Patches *patch.Library
+
+	// applied patches
+	Patched []string
+
+	// patches that could not be paired with selected roms
+	Unpaired []string
+
+	// number of games left out by curated lists
+	Filtered int
+
+	// roms skipped in all archives, indexed by normalized game name
+	Skips map[string][]*Skip
+
+	// games left out by storage budget
+	LeftOut []*rom.Game
+
+	// problems found in internal headers of selected roms, when headers are checked
+	HeaderIssues []string
+
+	// save files migrations, when a selected rom replaced a previously selected one
+	Migrated []string
+
+	// rom file names that replaced previously selected ones in output directory, indexed by previous file name
+	Renamed map[string]string
+
+	// true once roms to select have been computed
+	planned bool
+
+	// roms found in output directory before selected roms are moved, indexed by normalized game name
+	previous map[string][]*rom.Rom
+
+	// explanation messages of games left out by curated lists, indexed by normalized game name
+	filtered map[string]string
+
+	// report entries of games found in a preferred system sharing the same output directory, indexed by normalized game name
+	duplicates map[string]*ReportEntry
+
+	// processed archives, with their candidate roms
+	archives []*Archive
 }
 
 // New instanciates a new System
 func New(infos Infos, options *core.Options) *System {
 	return &System{
-		Infos:        infos,
-		Options:      options,
-		Games:        map[string]*rom.Game{},
-		RegionsStats: map[string]int{},
+		Infos:      infos,
+		Options:    options,
+		Games:      map[string]*rom.Game{},
+		Media:      map[string]map[string]string{},
+		Renamed:    map[string]string{},
+		Skips:      map[string][]*Skip{},
+		filtered:   map[string]string{},
+		duplicates: map[string]*ReportEntry{},
+	}
+}
+
+func (s *System) log(msg string) {
+	fmt.Printf("[%s] %s", s.Infos.Name, msg)
+}
+
+// Names returns the names that identify that system in settings: full name, name and output directory
+func (s *System) Names() []string {
+	return s.Infos.Names()
+}
+
+// override returns the manual override for given game name, or nil if none
+func (s *System) override(name string) *overrides.Override {
+	return s.Overrides.Find(s.Names(), name)
+}
+
+// MaxSize returns the storage budget of that system, 0 for no limit
+func (s *System) MaxSize() int64 {
+	for _, name := range s.Names() {
+		for key, size := range s.Options.SystemMaxSizes {
+			if strings.EqualFold(key, name) {
+				return size
+			}
+		}
+	}
+
+	return 0
+}
+
+// Weight returns the storage budget priority weight of that system, 1 by default
+func (s *System) Weight() int {
+	for _, name := range s.Names() {
+		for key, weight := range s.Options.SystemWeights {
+			if strings.EqualFold(key, name) {
+				return weight
+			}
+		}
+	}
+
+	return 1
+}
+
+// ListRank returns the position of given game in include list, or -1 if not listed
+func (s *System) ListRank(g *rom.Game) int {
+	if (s.Include == nil) || (len(g.Roms) == 0) {
+		return -1
+	}
+
+	var crcs []string
+	if s.Include.HaveCRC(s.Names()) {
+		crcs = gameCRCs(g)
+	}
+
+	e := s.Include.Find(s.Names(), g.Roms[0].Name, crcs)
+	if e == nil {
+		return -1
+	}
+
+	return s.Include.Rank(e)
+}
+
+// SelectedGames returns the number of games with selected roms
+func (s *System) SelectedGames() int {
+	result := 0
+
+	for _, g := range s.Games {
+		if len(g.Selected) > 0 {
+			result++
+		}
+	}
+
+	return result
+}
+
+// Size returns the total size of selected roms
+func (s *System) Size() int64 {
+	var result int64
+
+	for _, g := range s.Games {
+		result += GameSize(g)
 	}
+
+	return result
+}
+
+// GameSize returns the total size of given game selected roms, and of their patched copies once written
+func GameSize(g *rom.Game) int64 {
+	var result int64
+
+	for _, r := range g.OutputRoms() {
+		if size, err := r.ComputeSize(); err == nil {
+			result += size
+		}
+	}
+
+	return result
+}
+
+// bestRoms returns the roms to select for given game, honoring manual overrides
+func (s *System) bestRoms(g *rom.Game) []*rom.Rom {
+	if len(g.Roms) == 0 {
+		return nil
+	}
+
+	return OverrideBestRoms(g, s.Options, s.override(g.Roms[0].Name))
 }
 
 // RomsDir returns the roms directory name for that system
@@ -44,31 +250,498 @@ func (s *System) RomsDir() string {
 	return s.Infos.Dir
 }
 
-// ProcessArchive filters roms in given no-intro archive and outputs selected ones into given output directory
-func (s *System) ProcessArchive(archive string, outputDir string) error {
-	// ensure output directory
-	outputDir = path.Join(outputDir, s.RomsDir())
-	if err := os.MkdirAll(outputDir, 0777); (err != nil) && (err != os.ErrExist) {
-		return err
+// OutputDir returns the output directory path for that system
+func (s *System) OutputDir() string {
+	return path.Join(s.Options.Output, s.RomsDir())
+}
+
+// FindGame returns the game with given name, with a case insensitive and punctuation agnostic lookup
+func (s *System) FindGame(name string) *rom.Game {
+	return s.Games[rom.NormalizeTitle(name)]
+}
+
+// FillGamelist adds or updates gamelist entries for all selected roms
+func (s *System) FillGamelist(gl *gamelist.Gamelist) {
+	for _, g := range s.Games {
+		if len(g.Selected) == 0 {
+			continue
+		}
+
+		roms := g.Selected
+
+		if (len(g.Selected) > 1) && s.Options.Playlists {
+			// the playlist is the game entry
+			entry := s.gamelistEntry(g, g.Selected[0])
+			entry.Path = "./" + g.Selected[0].ReleaseName() + ".m3u"
+
+			gl.Set(entry)
+			roms = nil
+		}
+
+		for _, r := range append(roms, g.Patched...) {
+			entry := s.gamelistEntry(g, r)
+			if r.Part != "" {
+				entry.Name += " (" + r.Part + ")"
+			}
+
+			if label := patchedLabel(g, r); label != "" {
+				entry.Name += " " + label
+			}
+
+			gl.Set(entry)
+		}
 	}
+}
+
+// gamelistEntry returns a new gamelist entry for given rom
+func (s *System) gamelistEntry(g *rom.Game, r *rom.Rom) *gamelist.Game {
+	result := gamelist.NewGame(r.Filename, g.DisplayName())
+
+	result.Region = strings.Join(r.Regions, ", ")
+	result.Lang = strings.ToLower(strings.Join(r.Languages, ","))
+	result.Version = r.Version
+
+	if files := s.Media[r.Filename]; files != nil {
+		if files[media.Boxart] != "" {
+			result.Image = "./" + files[media.Boxart]
+		}
+
+		if files[media.Screenshot] != "" {
+			result.Thumbnail = "./" + files[media.Screenshot]
+		}
+
+		if files[media.Description] != "" {
+			if data, err := ioutil.ReadFile(path.Join(s.OutputDir(), files[media.Description])); err == nil {
+				result.Desc = strings.TrimSpace(string(data))
+			}
+		}
+	}
+
+	return result
+}
+
+// ProcessArchive collects candidate roms in given no-intro archive. Selected roms are moved to output directory when SelectRoms() is called.
+func (s *System) ProcessArchive(archive string) error {
+	a := NewArchive(s, archive, s.Options)
+
+	s.archives = append(s.archives, a)
+
+	start := time.Now()
+	defer func() {
+		s.Extracting += time.Since(start)
+	}()
+
+	s.BytesIn += pathSize(archive)
 
-	// process archive
-	a := NewArchive(s, archive, outputDir, s.Options)
 	if err := a.Process(); err != nil {
 		return err
 	}
 
-	// merge results
-	for name, game := range a.Games {
-		s.Games[name] = game
-	}
+	s.mergeGames(a.Games, archive)
 
 	s.Processed += a.Processed
 	s.Skipped += a.Skipped
+	s.Seen += a.Seen
+
+	return nil
+}
+
+// mergeGames adds given games found in given archive to candidates, a game can be found in several archives
+func (s *System) mergeGames(games map[string]*rom.Game, archive string) {
+	for key, game := range games {
+		if s.Games[key] == nil {
+			s.Games[key] = game
+		} else {
+			if s.Options.Debug {
+				s.log(fmt.Sprintf("Merging '%s' candidates from: %s\n", game.Name, path.Base(archive)))
+			}
+
+			for _, r := range game.Roms {
+				// archives are processed newest first, so the newest copy of a rom wins
+				if s.Games[key].FindRom(r.Filename) != nil {
+					if s.Options.Debug {
+						s.log(fmt.Sprintf("Skipped older copy of '%s' from: %s\n", r.Filename, path.Base(archive)))
+					}
+
+					continue
+				}
+
+				s.Games[key].AddRom(r)
+			}
+		}
+	}
+}
+
+// PlanRoms computes the roms to select for each game, without moving them
+func (s *System) PlanRoms() {
+	if s.planned {
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		s.Selecting += time.Since(start)
+	}()
+
+	for key, g := range s.Games {
+		if skip, msg := FilterGame(g, s.Include, s.Exclude, s.Names()); skip {
+			if s.Options.Debug {
+				s.log(fmt.Sprintf("Skipped '%s': %s\n", g.Name, msg))
+			}
+
+			s.Filtered++
+			s.filtered[key] = msg
+			g.Selected = nil
+
+			continue
+		}
+
+		g.Selected = s.bestRoms(g)
+		s.checkUnpaired(g)
+	}
+
+	s.planned = true
+}
+
+// LeaveOut unselects given game
+func (s *System) LeaveOut(g *rom.Game) {
+	g.Selected = nil
+	s.LeftOut = append(s.LeftOut, g)
+}
+
+// SelectRoms moves planned roms of each game to output directory, then deletes all extracted archives
+func (s *System) SelectRoms() error {
+	s.PlanRoms()
+
+	start := time.Now()
+	defer func() {
+		s.Selecting += time.Since(start)
+	}()
 
-	for region, nb := range a.RegionsStats {
-		s.RegionsStats[region] += nb
+	if s.Options.DryRun {
+		// extracted files are deleted on cleanup
+		s.BytesOut = s.Size()
+
+		if s.Options.Report {
+			// completeness report holds CRC32 of selected roms, computed before extracted files are deleted
+			if err := s.computeCRCs(); err != nil {
+				return err
+			}
+		}
+
+		return s.cleanup()
+	}
+
+	// ensure output directory
+	if err := os.MkdirAll(s.OutputDir(), 0777); (err != nil) && (err != os.ErrExist) {
+		return err
+	}
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Moving all %v selected games to: %s\n", len(s.Games), s.OutputDir()))
+	}
+
+	var library *media.Library
+	if s.Options.MediaDir != "" {
+		library = media.New(s.Options.MediaDir)
+	}
+
+	if err := s.scanPrevious(); err != nil {
+		return err
+	}
+
+	for _, g := range s.Games {
+		if err := s.moveGameRoms(g); err != nil {
+			return err
+		}
+
+		if g.Moved {
+			s.BytesOut += GameSize(g)
+
+			if err := s.migrateSaves(g); err != nil {
+				return err
+			}
+		}
+
+		if (library != nil) && g.Moved {
+			if err := s.installMedia(library, g); err != nil {
+				return err
+			}
+		}
+
+		if s.Options.CheckHeaders && g.Moved {
+			if err := s.checkHeaders(g); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.cleanup()
+}
+
+// computeCRCs computes CRC32 of selected roms
+func (s *System) computeCRCs() error {
+	for _, g := range s.Games {
+		for _, r := range g.Selected {
+			if _, err := r.ComputeCRC(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkHeaders inspects internal headers of selected roms of given game
+func (s *System) checkHeaders(g *rom.Game) error {
+	for _, r := range g.Selected {
+		check, err := CheckRom(r, s.Names(), s.Options)
+		if err != nil {
+			return err
+		}
+
+		if (check != nil) && ((len(check.Issues) > 0) || (check.Region != "")) {
+			s.HeaderIssues = append(s.HeaderIssues, fmt.Sprintf("%s: %s", r.Filename, check))
+		}
+	}
+
+	return nil
+}
+
+// Release moves planned roms out of extracted archives into a staging directory, then deletes all extracted archives. With a storage budget, roms are selected once all systems have been processed, so that temporary directory only holds planned roms instead of all archives.
+func (s *System) Release() error {
+	s.PlanRoms()
+
+	dir := s.stagingDir()
+
+	for _, g := range s.Games {
+		for _, r := range g.Selected {
+			if !helpers.IsInDir(r.File, s.Options.Tmp) {
+				// rom of an already extracted directory
+				continue
+			}
+
+			if err := os.MkdirAll(dir, 0777); (err != nil) && (err != os.ErrExist) {
+				return err
+			}
+
+			filePath := path.Join(dir, r.Filename)
+
+			if s.Options.Debug {
+				s.log(fmt.Sprintf("Staging '%s' into: %s\n", r.Filename, dir))
+			}
+
+			if err := os.Rename(r.File, filePath); err != nil {
+				return err
+			}
+
+			r.File = filePath
+		}
+	}
+
+	return s.cleanupArchives()
+}
+
+// stagingDir returns the directory path of roms released from extracted archives
+func (s *System) stagingDir() string {
+	return path.Join(s.Options.Tmp, s.Infos.FullName()+" (staged)")
+}
+
+// cleanup deletes all extracted archives and staged roms
+func (s *System) cleanup() error {
+	if err := s.cleanupArchives(); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(s.stagingDir())
+}
+
+// cleanupArchives deletes all extracted archives
+func (s *System) cleanupArchives() error {
+	for _, a := range s.archives {
+		if err := a.Cleanup(); err != nil {
+			return err
+		}
+	}
+
+	s.archives = nil
+
+	return nil
+}
+
+// moveFile moves given file into given directory. Files outside tmp directory come from an input directory, so they are copied instead.
+func (s *System) moveFile(filePath string, dir string) error {
+	if !helpers.IsInDir(filePath, s.Options.Tmp) {
+		if s.Options.Debug {
+			s.log(fmt.Sprintf("Copying '%s' into: %s\n", filePath, dir))
+		}
+
+		return helpers.CopyFile(filePath, dir)
 	}
 
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Moving '%s' into: %s\n", filePath, dir))
+	}
+
+	return os.Rename(filePath, dir)
+}
+
+// moveGameRoms moves selected roms of given game to output directory, with all its parts when that is a multi-part release. Patched copies written next to selected roms are recorded in game Patched roms.
+func (s *System) moveGameRoms(g *rom.Game) error {
+	if g.Moved {
+		// game was already moved
+		return nil
+	}
+
+	roms := g.Selected
+	if len(roms) == 0 {
+		// no rom matches filtering criteria
+		return nil
+	}
+
+	moved := []*rom.Rom{}
+	patchedRoms := []*rom.Rom{}
+
+	for _, r := range roms {
+		outputPath := path.Join(s.OutputDir(), r.Filename)
+
+		if err := s.moveFile(r.File, outputPath); err != nil {
+			return err
+		}
+
+		r.File = outputPath
+
+		patched, keep, err := s.applyPatch(r)
+		if err != nil {
+			return err
+		}
+
+		if !keep {
+			// rom could not be patched
+			continue
+		}
+
+		moved = append(moved, r)
+
+		if patched != nil {
+			patchedRoms = append(patchedRoms, patched)
+		}
+	}
+
+	if len(moved) == 0 {
+		g.Selected = nil
+		return nil
+	}
+
+	roms = moved
+
+	for _, r := range append(append([]*rom.Rom{}, roms...), patchedRoms...) {
+		if err := s.formatHeader(r); err != nil {
+			return err
+		}
+
+		if err := s.formatN64Rom(r); err != nil {
+			return err
+		}
+
+		if s.Group != nil {
+			s.Group.claim(s, r.Filename)
+		}
+	}
+
+	if (len(roms) > 1) && s.Options.Playlists {
+		if err := s.writePlaylist(roms); err != nil {
+			return err
+		}
+	}
+
+	g.Moved = true
+	g.Selected = roms
+	g.Patched = patchedRoms
+
 	return nil
 }
+
+// writePlaylist writes a m3u playlist for given parts of a multi-part release
+func (s *System) writePlaylist(roms []*rom.Rom) error {
+	filePath := path.Join(s.OutputDir(), roms[0].ReleaseName()+".m3u")
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Writing playlist: %s\n", filePath))
+	}
+
+	if s.Group != nil {
+		s.Group.claim(s, path.Base(filePath))
+	}
+
+	content := ""
+	for _, r := range roms {
+		content += r.Filename + "\n"
+	}
+
+	return ioutil.WriteFile(filePath, []byte(content), 0644)
+}
+
+// installMedia copies or links media files of given moved game from given library to output directory
+func (s *System) installMedia(library *media.Library, g *rom.Game) error {
+	systemDirs := []string{s.Infos.FullName(), s.Infos.Dir}
+	found := false
+
+	for _, r := range g.OutputRoms() {
+		// media are indexed with the CRC32 of no-intro dumps
+		crc := r.DumpCRC
+		if crc == "" {
+			var err error
+			if crc, err = r.ComputeCRC(); err != nil {
+				s.log(fmt.Sprintf("ERR: Failed to compute CRC of '%s': %v\n", r.Filename, err))
+			}
+		}
+
+		files := library.Find(systemDirs, crc, helpers.FileBase(r.Filename))
+		if len(files) == 0 {
+			continue
+		}
+
+		found = true
+		s.Media[r.Filename] = map[string]string{}
+
+		for kind, filePath := range files {
+			relPath := path.Join(mediaDir, kind, helpers.FileBase(r.Filename)+path.Ext(filePath))
+
+			if err := s.installMediaFile(filePath, path.Join(s.OutputDir(), relPath)); err != nil {
+				return err
+			}
+
+			s.Media[r.Filename][kind] = relPath
+		}
+	}
+
+	if !found {
+		s.MissingMedia = append(s.MissingMedia, g)
+	}
+
+	return nil
+}
+
+// installMediaFile copies or links given media file to given output path
+func (s *System) installMediaFile(filePath string, outputPath string) error {
+	if err := os.MkdirAll(path.Dir(outputPath), 0777); (err != nil) && (err != os.ErrExist) {
+		return err
+	}
+
+	if s.Options.Debug {
+		s.log(fmt.Sprintf("Installing media '%s' into: %s\n", filePath, outputPath))
+	}
+
+	if s.Options.MediaLink {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return err
+		}
+
+		os.Remove(outputPath)
+		return os.Symlink(absPath, outputPath)
+	}
+
+	return helpers.CopyFile(filePath, outputPath)
+}
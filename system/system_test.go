@@ -0,0 +1,72 @@
+package system
+
+import (
+	"path"
+	"testing"
+
+	"github.com/aymerick/charette/core"
+	"github.com/aymerick/charette/rom"
+)
+
+// newArchiveGames returns the games of given rom files, as found in an archive
+func newArchiveGames(fileNames ...string) map[string]*rom.Game {
+	result := map[string]*rom.Game{}
+
+	for _, fileName := range fileNames {
+		r := rom.MustFill(fileName)
+
+		key := rom.NormalizeTitle(r.Name)
+		if result[key] == nil {
+			result[key] = rom.NewGame()
+		}
+
+		result[key].AddRom(r)
+	}
+
+	return result
+}
+
+func TestMergeGames(t *testing.T) {
+	s := New(Infos{}, core.NewOptions())
+
+	s.mergeGames(newArchiveGames("new/Tetris (Europe).gb"), "new.7z")
+	s.mergeGames(newArchiveGames("old/Tetris (Europe).gb", "old/Tetris (USA).gb", "old/Kwirk (USA).gb"), "old.7z")
+
+	tests := []struct {
+		game  string
+		files []string
+	}{
+		// roms of a game found in several archives are merged, archives are processed newest first so the newest copy wins
+		{"Tetris", []string{"new/Tetris (Europe).gb", "old/Tetris (USA).gb"}},
+		{"Kwirk", []string{"old/Kwirk (USA).gb"}},
+	}
+
+	if len(s.Games) != len(tests) {
+		t.Errorf("Failed to merge candidates, got %d games but expected %d", len(s.Games), len(tests))
+	}
+
+	for _, test := range tests {
+		g := s.Games[rom.NormalizeTitle(test.game)]
+		if g == nil {
+			t.Errorf("Failed to merge candidates, game '%s' not found", test.game)
+			continue
+		}
+
+		files := []string{}
+		for _, r := range g.Roms {
+			files = append(files, path.Join(path.Base(path.Dir(r.File)), r.Filename))
+		}
+
+		if len(files) != len(test.files) {
+			t.Errorf("Failed to merge candidates of '%s', got %v but expected %v", test.game, files, test.files)
+			continue
+		}
+
+		for i, file := range files {
+			if file != test.files[i] {
+				t.Errorf("Failed to merge candidates of '%s', got %v but expected %v", test.game, files, test.files)
+				break
+			}
+		}
+	}
+}